@@ -0,0 +1,267 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composeFile is the part of a compose file used to generate bridge.yaml.
+type composeFile struct {
+	Name     string
+	Services []composeService // In file order
+}
+
+// composeService is a service of a compose file.
+type composeService struct {
+	Name          string
+	Image         string          `yaml:"image"`
+	ContainerName string          `yaml:"container_name"`
+	WorkingDir    string          `yaml:"working_dir"`
+	Volumes       []composeVolume `yaml:"volumes"`
+}
+
+// composeVolume is a service volume in either the short ("src:dst:ro") or
+// the long (type/source/target) syntax.
+type composeVolume struct {
+	Type   string `yaml:"type"`
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+}
+
+// UnmarshalYAML accepts the short volume syntax. A source that looks like a
+// path is a bind mount; anything else names a volume.
+func (v *composeVolume) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		type plain composeVolume
+		return node.Decode((*plain)(v))
+	}
+
+	parts := strings.Split(node.Value, ":")
+	if len(parts) == 1 {
+		*v = composeVolume{Type: "volume", Target: parts[0]}
+		return nil
+	}
+	v.Source, v.Target = parts[0], parts[1]
+	v.Type = "volume"
+	if strings.HasPrefix(v.Source, ".") || strings.HasPrefix(v.Source, "/") || strings.HasPrefix(v.Source, "~") {
+		v.Type = "bind"
+	}
+	return nil
+}
+
+// readComposeFile parses a compose file. Relative bind mount sources are
+// resolved against the file's directory.
+func readComposeFile(path string) (*composeFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file %s: %w", path, err)
+	}
+
+	var doc struct {
+		Name     string    `yaml:"name"`
+		Services yaml.Node `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML in %s: %w", path, err)
+	}
+	if doc.Services.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s has no services", path)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	file := &composeFile{Name: doc.Name}
+	for i := 0; i+1 < len(doc.Services.Content); i += 2 {
+		service := composeService{Name: doc.Services.Content[i].Value}
+		if err := doc.Services.Content[i+1].Decode(&service); err != nil {
+			return nil, fmt.Errorf("%s: service '%s': %w", path, service.Name, err)
+		}
+		for j, v := range service.Volumes {
+			if v.Type == "bind" {
+				service.Volumes[j].Source = resolveHostPath(dir, v.Source)
+			}
+		}
+		file.Services = append(file.Services, service)
+	}
+	return file, nil
+}
+
+// resolveHostPath makes a bind mount source absolute.
+func resolveHostPath(dir, source string) string {
+	if source == "~" || strings.HasPrefix(source, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, source[1:])
+		}
+		return source
+	}
+	if filepath.IsAbs(source) {
+		return filepath.Clean(source)
+	}
+	return filepath.Join(dir, source)
+}
+
+// projectName returns the compose project name the way docker compose
+// derives it: explicit, then $COMPOSE_PROJECT_NAME, then the file's name
+// key, then the directory holding the file.
+func (f *composeFile) projectName(explicit, path string) string {
+	name := explicit
+	if name == "" {
+		name = os.Getenv("COMPOSE_PROJECT_NAME")
+	}
+	if name == "" {
+		name = f.Name
+	}
+	if name == "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			name = filepath.Base(filepath.Dir(abs))
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if 'a' <= r && r <= 'z' || '0' <= r && r <= '9' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// bridgeService returns the service the bridge runs in: the named one, or
+// else one using the claude-sidecar image or called "claude".
+func (f *composeFile) bridgeService(name string) (*composeService, error) {
+	for i := range f.Services {
+		if name != "" && f.Services[i].Name == name {
+			return &f.Services[i], nil
+		}
+	}
+	if name != "" {
+		return nil, fmt.Errorf("service '%s' not found", name)
+	}
+	for i := range f.Services {
+		if strings.Contains(f.Services[i].Image, "claude-sidecar") {
+			return &f.Services[i], nil
+		}
+	}
+	for i := range f.Services {
+		if f.Services[i].Name == "claude" {
+			return &f.Services[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// composeMounts converts service volumes to container mounts.
+func composeMounts(volumes []composeVolume) []containerMount {
+	mounts := make([]containerMount, 0, len(volumes))
+	for _, v := range volumes {
+		mounts = append(mounts, containerMount{Type: v.Type, Source: v.Source, Destination: v.Target})
+	}
+	return mounts
+}
+
+// buildComposePlan turns the services of a compose file into a plan. Each
+// service gets the catalogue commands its image or name suggests; services
+// without any are left out, except keep.
+func buildComposePlan(file *composeFile, project string, bridge *composeService, keep string) initPlan {
+	var containers []initContainer
+	for _, s := range file.Services {
+		if bridge != nil && s.Name == bridge.Name {
+			continue
+		}
+		name := s.ContainerName
+		if name == "" {
+			name = project + "-" + s.Name + "-1"
+		}
+		c := initContainer{Info: containerInfo{Name: name, Image: s.Image, WorkingDir: s.WorkingDir, Project: project, Service: s.Name}}
+		for _, tool := range toolCatalogue {
+			if matchesFamily(tool, c) {
+				c.Tools = append(c.Tools, tool)
+			}
+		}
+		if len(c.Tools) == 0 && s.Name != keep {
+			continue
+		}
+		if bridge != nil {
+			if paths := mountPaths(composeMounts(bridge.Volumes), composeMounts(s.Volumes)); len(paths) > 0 {
+				c.Paths = paths
+			}
+		}
+		containers = append(containers, c)
+	}
+	return buildInitPlan(project, containers)
+}
+
+// configImportComposeCommand implements `bridge config import-compose`.
+func configImportComposeCommand(args []string, configPath string) int {
+	fs := flag.NewFlagSet("config import-compose", flag.ContinueOnError)
+	project := fs.String("project", "", "Compose project name (default: as docker compose derives it)")
+	bridgeName := fs.String("bridge-service", "", "Service the bridge runs in (default: the claude-sidecar service)")
+	force := fs.Bool("force", false, "Overwrite an existing config file")
+	dryRun := fs.Bool("dry-run", false, "Print the config instead of writing it")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: bridge config import-compose [flags] <compose file>")
+		return 1
+	}
+	composePath := fs.Arg(0)
+
+	path, err := editTarget(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if _, err := os.Stat(path); err == nil && !*force && !*dryRun {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use --force to overwrite it)\n", path)
+		return 1
+	}
+
+	data, plan, err := importCompose(composePath, *project, *bridgeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if *dryRun {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := writeNewConfig(path, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s with %d command(s) in %d container(s)\n", path, len(plan.Commands), len(plan.Containers))
+	return 0
+}
+
+// importCompose generates bridge.yaml from a compose file.
+func importCompose(composePath, project, bridgeName string) ([]byte, initPlan, error) {
+	file, err := readComposeFile(composePath)
+	if err != nil {
+		return nil, initPlan{}, err
+	}
+	bridge, err := file.bridgeService(bridgeName)
+	if err != nil {
+		return nil, initPlan{}, fmt.Errorf("%s: %w", composePath, err)
+	}
+	if bridge == nil {
+		fmt.Fprintf(os.Stderr, "No claude-sidecar service found in %s; not deriving path mappings (use --bridge-service)\n", composePath)
+	}
+
+	plan := buildComposePlan(file, file.projectName(project, composePath), bridge, "")
+	plan.prune()
+	if len(plan.Commands) == 0 {
+		return nil, plan, fmt.Errorf("no service in %s uses an image with known commands", composePath)
+	}
+
+	header := "# Generated by `bridge config import-compose` from " + filepath.Base(composePath) + "."
+	data, err := renderPlan(plan, header, "# Commands picked from each service's image or name.")
+	return data, plan, err
+}
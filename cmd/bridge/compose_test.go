@@ -0,0 +1,146 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const composeFixture = `name: MyProject
+services:
+  claude:
+    image: ghcr.io/mithredate/claude-sidecar:latest
+    volumes:
+      - .:/workspace
+      - claude-config:/home/claude/.claude
+  app:
+    image: php:8.3-fpm
+    volumes:
+      - .:/var/www/html
+    working_dir: /var/www/html
+  frontend:
+    image: node:20-alpine
+    container_name: myproject-frontend
+    volumes:
+      - type: bind
+        source: ./frontend
+        target: /app
+    working_dir: /app
+  viewer:
+    image: node:20-alpine
+  db:
+    image: postgres:16
+    volumes:
+      - db-data:/var/lib/postgresql/data
+  redis:
+    image: redis:7
+`
+
+func TestReadComposeFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"compose.yaml": composeFixture})
+
+	file, err := readComposeFile(filepath.Join(dir, "compose.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, s := range file.Services {
+		names = append(names, s.Name)
+	}
+	if want := []string{"claude", "app", "frontend", "viewer", "db", "redis"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("services = %q, want %q", names, want)
+	}
+
+	wantClaude := []composeVolume{
+		{Type: "bind", Source: dir, Target: "/workspace"},
+		{Type: "volume", Source: "claude-config", Target: "/home/claude/.claude"},
+	}
+	if !reflect.DeepEqual(file.Services[0].Volumes, wantClaude) {
+		t.Errorf("claude volumes = %+v, want %+v", file.Services[0].Volumes, wantClaude)
+	}
+	wantFrontend := []composeVolume{{Type: "bind", Source: filepath.Join(dir, "frontend"), Target: "/app"}}
+	if !reflect.DeepEqual(file.Services[2].Volumes, wantFrontend) {
+		t.Errorf("frontend volumes = %+v, want %+v", file.Services[2].Volumes, wantFrontend)
+	}
+}
+
+func TestComposeProjectName(t *testing.T) {
+	t.Setenv("COMPOSE_PROJECT_NAME", "")
+	file := &composeFile{}
+	if got := file.projectName("", "/home/dev/My.Project/compose.yaml"); got != "myproject" {
+		t.Errorf("projectName from directory = %q", got)
+	}
+	file.Name = "Shop"
+	if got := file.projectName("", "compose.yaml"); got != "shop" {
+		t.Errorf("projectName from name key = %q", got)
+	}
+	t.Setenv("COMPOSE_PROJECT_NAME", "env")
+	if got := file.projectName("", "compose.yaml"); got != "env" {
+		t.Errorf("projectName from env = %q", got)
+	}
+	if got := file.projectName("flag", "compose.yaml"); got != "flag" {
+		t.Errorf("projectName explicit = %q", got)
+	}
+}
+
+func TestImportCompose(t *testing.T) {
+	t.Setenv("COMPOSE_PROJECT_NAME", "")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"compose.yaml": composeFixture})
+
+	data, _, err := importCompose(filepath.Join(dir, "compose.yaml"), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "# Generated by `bridge config import-compose` from compose.yaml.\n" +
+		`# See examples/claude-bridge.yaml for everything bridge.yaml can do.
+version: "2"
+
+# Logical container names mapped to the actual container names.
+# Commands run in their container's working directory.
+containers:
+  app:
+    name: myproject-app-1 # image: php:8.3-fpm
+    workdir: /var/www/html
+    paths:
+      /workspace: /var/www/html
+  db:
+    name: myproject-db-1 # image: postgres:16
+  frontend:
+    name: myproject-frontend # image: node:20-alpine
+    workdir: /app
+    paths:
+      /workspace/frontend: /app
+
+# Commands picked from each service's image or name.
+commands:
+  php:
+    container: app
+    exec: [php]
+  composer:
+    container: app
+    exec: [composer]
+  node:
+    container: frontend
+    exec: [node]
+  npm:
+    container: frontend
+    exec: [npm]
+  npx:
+    container: frontend
+    exec: [npx]
+  psql:
+    container: db
+    exec: [psql]
+`
+	if string(data) != want {
+		t.Errorf("config =\n%s\nwant\n%s", data, want)
+	}
+
+	if _, _, err := importCompose(filepath.Join(dir, "compose.yaml"), "", "nope"); err == nil || !contains(err.Error(), "service 'nope' not found") {
+		t.Errorf("expected unknown service error, got %v", err)
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Images used by `bridge compose generate`.
+const (
+	bridgeImage      = "ghcr.io/mithredate/claude-sidecar:latest"
+	socketProxyImage = "tecnativa/docker-socket-proxy:latest"
+	fallbackImage    = "alpine:3"
+)
+
+// toolImages maps a tool to the image a generated sidecar for it uses.
+// Tools are looked up in toolCatalogue order, so a container running both
+// php and node commands gets the php image.
+var toolImages = map[string]string{
+	"php":      "php:8.3-cli",
+	"composer": "composer:2",
+	"node":     "node:20-alpine",
+	"npm":      "node:20-alpine",
+	"npx":      "node:20-alpine",
+	"go":       "golang:1.24-alpine",
+	"python":   "python:3.12-slim",
+	"pip":      "python:3.12-slim",
+	"ruby":     "ruby:3.3",
+	"bundle":   "ruby:3.3",
+	"cargo":    "rust:1",
+}
+
+// composeCommand implements `bridge compose`.
+func composeCommand(config *Config, configPath string, args []string) int {
+	if len(args) == 0 || args[0] != "generate" {
+		fmt.Fprintln(os.Stderr, "Usage: bridge compose generate [--workspace DIR] [--image CONTAINER=IMAGE]... [-o FILE] [--force]")
+		if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			return 0
+		}
+		return 1
+	}
+
+	fs := flag.NewFlagSet("compose generate", flag.ContinueOnError)
+	workspace := fs.String("workspace", "", "Where the claude service mounts the project (default: inferred from the path mappings, else /workspaces/<project directory>)")
+	images := keyValueFlag{}
+	fs.Var(images, "image", "Image for a logical container, as CONTAINER=IMAGE (repeatable)")
+	output := fs.String("o", "", "Write the compose file here instead of to stdout")
+	fs.StringVar(output, "output", "", "Write the compose file here instead of to stdout")
+	force := fs.Bool("force", false, "Overwrite an existing output file")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 1
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Error: unexpected argument '%s'\n", fs.Arg(0))
+		return 1
+	}
+
+	if *workspace == "" {
+		*workspace = inferWorkspace(config)
+	}
+	if *workspace == "" {
+		*workspace = "/workspaces/" + filepath.Base(projectDir(configPath))
+	}
+	data, err := generateCompose(config, *workspace, images, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if _, err := os.Stat(*output); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use --force to overwrite it)\n", *output)
+		return 1
+	}
+	if err := writeFileAtomic(*output, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s with %d sidecar(s)\n", *output, len(sidecarNames(config)))
+	return 0
+}
+
+// projectDir returns the directory the project config belongs to: the
+// parent of its .sidecar directory, or the directory of a --config file.
+func projectDir(configPath string) string {
+	file, err := editTarget(configPath)
+	if err != nil {
+		file = filepath.SplitList(configPath)[0]
+	}
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		dir = filepath.Dir(file)
+	}
+	if filepath.Base(dir) == projectConfigDir {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+// generateCompose renders a compose file with the claude service, the socket
+// proxy and a sidecar per logical container. The claude service mounts the
+// project directory at workspace; each sidecar mounts the parts of it that
+// its path mappings name, or the whole project at the same path when it has
+// none. Mappings outside the workspace are reported on warn and skipped.
+func generateCompose(config *Config, workspace string, images map[string]string, warn io.Writer) ([]byte, error) {
+	workspace = path.Clean(workspace)
+	names := sidecarNames(config)
+	for name := range images {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("--image names container '%s', which is not in the config", name)
+		}
+	}
+
+	services := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	proxy := mappingNode(
+		"image", scalarNode(socketProxyImage),
+		"restart", scalarNode("unless-stopped"),
+		"environment", mappingNode("CONTAINERS", scalarNode("1"), "EXEC", scalarNode("1"), "POST", scalarNode("1")),
+		"volumes", listNode("/var/run/docker.sock:/var/run/docker.sock:ro"),
+	)
+	proxyKey := scalarNode("socket-proxy")
+	proxyKey.HeadComment = "# Limits the bridge's Docker API access to listing and exec."
+	services.Content = append(services.Content, proxyKey, proxy)
+
+	dependsOn := []string{"socket-proxy"}
+	var sidecarNodes []*yaml.Node
+	for _, name := range names {
+		if name == "claude" || name == "socket-proxy" {
+			return nil, fmt.Errorf("container '%s' clashes with a generated service; rename it in the config", name)
+		}
+		c, declared := config.Containers[name]
+		if !declared {
+			fmt.Fprintf(warn, "Container '%s' is used by commands but not under containers; its sidecar is named %s\n", name, config.ResolveContainer(name))
+		}
+		paths := sidecarPaths(config, name)
+
+		var volumes []string
+		for _, host := range sortedKeys(paths) {
+			rel, ok := workspaceRelative(workspace, host)
+			if !ok {
+				fmt.Fprintf(warn, "Not mounting %s for container '%s': it is outside the workspace %s\n", host, name, workspace)
+				continue
+			}
+			volumes = append(volumes, rel+":"+paths[host])
+		}
+		workdir := c.Workdir
+		wholeProject := len(volumes) == 0
+		if wholeProject {
+			volumes = append(volumes, ".:"+workspace)
+			if workdir == "" {
+				workdir = workspace
+			}
+		}
+		sort.Strings(volumes)
+
+		// A working directory outside the mounts would start the sidecar
+		// in an empty directory
+		if workdir != "" && !mountedIn(workdir, volumes) {
+			fmt.Fprintf(warn, "Not using workdir %s for container '%s': it is not mounted\n", workdir, name)
+			workdir = ""
+			if wholeProject {
+				workdir = workspace
+			}
+		}
+
+		image, known := images[name], true
+		if image == "" {
+			image, known = sidecarImage(config, name)
+		}
+		imageNode := scalarNode(image)
+		if !known {
+			imageNode.LineComment = "# TODO: no image known for this container's commands"
+		}
+
+		service := mappingNode(
+			"image", imageNode,
+			"container_name", scalarNode(config.ResolveContainer(name)),
+			"command", argvNode([]string{"sleep", "infinity"}),
+			"volumes", listNode(volumes...),
+		)
+		if workdir != "" {
+			service.Content = append(service.Content, scalarNode("working_dir"), scalarNode(workdir))
+		}
+		sidecarNodes = append(sidecarNodes, scalarNode(name), service)
+		dependsOn = append(dependsOn, name)
+	}
+
+	claude := mappingNode(
+		"image", scalarNode(bridgeImage),
+		"depends_on", listNode(dependsOn...),
+		"cap_add", listNode("NET_ADMIN", "NET_RAW"),
+		"environment", mappingNode("DOCKER_HOST", scalarNode("tcp://socket-proxy:2375")),
+		// The home volume must come before the config volume inside it
+		"volumes", listNode(".:"+workspace, "claude-home:/home/claude/", "claude-config:/home/claude/.claude"),
+		"working_dir", scalarNode(workspace),
+		"stdin_open", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"},
+		"tty", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"},
+	)
+	claudeKey := scalarNode("claude")
+	claudeKey.HeadComment = "# Claude Code, routing commands to the sidecars through the bridge.\n" +
+		"# NET_ADMIN and NET_RAW let it set up its network firewall."
+	services.Content = append(services.Content, claudeKey, claude)
+
+	if len(sidecarNodes) > 0 {
+		sidecarNodes[0].HeadComment = "# One sidecar per logical container in bridge.yaml. Each stays up\n# (sleep infinity) so the bridge can exec commands in it."
+		services.Content = append(services.Content, sidecarNodes...)
+	}
+
+	servicesKey := scalarNode("services")
+	servicesKey.HeadComment = "# Generated by `bridge compose generate` from bridge.yaml.\n" +
+		"# Paths are relative to the project directory; save this file there."
+	root := mappingNode(
+		"volumes", mappingNode(
+			"claude-config", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: ""},
+			"claude-home", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: ""},
+		),
+	)
+	root.Content = append([]*yaml.Node{servicesKey, services}, root.Content...)
+
+	for i := 2; i < len(services.Content); i += 2 {
+		services.Content[i].HeadComment = joinComments(blankLineMarker, services.Content[i].HeadComment)
+	}
+	root.Content[2].HeadComment = blankLineMarker
+
+	d := &configDocument{doc: &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}, markers: true}
+	return d.bytes()
+}
+
+// joinComments joins comment lines, skipping empty ones.
+func joinComments(lines ...string) string {
+	var kept []string
+	for _, line := range lines {
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// sidecarNames returns the logical containers that get a sidecar, sorted:
+// those under containers and any other a command, scope, route or select_by
+// candidate names.
+func sidecarNames(config *Config) []string {
+	names := make(map[string]bool)
+	for name := range config.Containers {
+		names[name] = true
+	}
+	addCommand := func(cmd Command) {
+		names[cmd.Container] = true
+		for _, r := range cmd.Routes {
+			names[r.Container] = true
+		}
+		if cmd.SelectBy != nil {
+			for _, c := range cmd.SelectBy.Candidates {
+				names[c.Container] = true
+			}
+		}
+	}
+	for _, cmd := range config.Commands {
+		addCommand(cmd)
+	}
+	for _, scope := range config.Scopes {
+		for _, cmd := range scope.Commands {
+			addCommand(cmd)
+		}
+	}
+	delete(names, "")
+	return sortedKeys(names)
+}
+
+// inferWorkspace returns where the config's path mappings expect the
+// project in the claude service: the mapped host path containing the most
+// other mapped paths, the shortest on a tie. It returns "" when nothing is
+// mapped.
+func inferWorkspace(config *Config) string {
+	var sources []string
+	for _, name := range sidecarNames(config) {
+		for host := range sidecarPaths(config, name) {
+			sources = append(sources, path.Clean(host))
+		}
+	}
+	best, bestCount := "", 0
+	for _, candidate := range sources {
+		count := 0
+		for _, source := range sources {
+			if _, ok := workspaceRelative(candidate, source); ok {
+				count++
+			}
+		}
+		if count > bestCount || (count == bestCount && len(candidate) < len(best)) {
+			best, bestCount = candidate, count
+		}
+	}
+	return best
+}
+
+// mountedIn reports whether dir is at or below the target of one of the
+// compose volumes.
+func mountedIn(dir string, volumes []string) bool {
+	for _, v := range volumes {
+		_, target, _ := strings.Cut(v, ":")
+		if _, ok := workspaceRelative(path.Clean(target), dir); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sidecarPaths collects the explicit path mappings of a container and of
+// the commands routed to it.
+func sidecarPaths(config *Config, container string) map[string]string {
+	paths := make(map[string]string)
+	for host, target := range config.Containers[container].Paths.explicit() {
+		paths[host] = target
+	}
+	for _, name := range sortedKeys(config.Commands) {
+		cmd := config.Commands[name]
+		if cmd.Container != container {
+			continue
+		}
+		for host, target := range cmd.Paths.explicit() {
+			paths[host] = target
+		}
+	}
+	return paths
+}
+
+// sidecarImage picks an image for a container from the tools its commands
+// run. The second result is false when no command names a known tool.
+func sidecarImage(config *Config, container string) (string, bool) {
+	tools := make(map[string]bool)
+	for _, cmd := range config.Commands {
+		if cmd.Container == container && len(cmd.Exec) > 0 {
+			tools[path.Base(cmd.Exec[0])] = true
+		}
+	}
+	for _, tool := range toolCatalogue {
+		if image, ok := toolImages[tool]; ok && tools[tool] {
+			return image, true
+		}
+	}
+	return fallbackImage, false
+}
+
+// workspaceRelative returns a path under workspace relative to the project
+// directory, as a compose bind mount source.
+func workspaceRelative(workspace, p string) (string, bool) {
+	p = path.Clean(p)
+	if p == workspace {
+		return ".", true
+	}
+	if rest, ok := strings.CutPrefix(p, workspace+"/"); ok {
+		return "./" + rest, true
+	}
+	return "", false
+}
+
+// mappingNode returns a mapping of the given key and value node pairs, in
+// order.
+func mappingNode(pairs ...any) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i < len(pairs); i += 2 {
+		node.Content = append(node.Content, scalarNode(pairs[i].(string)), pairs[i+1].(*yaml.Node))
+	}
+	return node
+}
+
+// listNode returns a block-style list of strings.
+func listNode(items ...string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, item := range items {
+		node.Content = append(node.Content, scalarNode(item))
+	}
+	return node
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenerateCompose(t *testing.T) {
+	config := &Config{
+		Containers: map[string]ContainerConfig{
+			"app":   {Name: "shop-app-1", Workdir: "/var/www/html", Paths: PathMap{"/workspace": "/var/www/html"}},
+			"front": {Name: "shop-front-1"},
+			"db":    {Name: "shop-db-1"},
+		},
+		Commands: map[string]Command{
+			"php":  {Container: "app", Exec: []string{"php"}},
+			"node": {Container: "front", Exec: []string{"/usr/local/bin/node"}, Paths: PathMap{"/workspace/ui": "/ui", "/srv/shared": "/shared"}},
+			"psql": {Container: "db", Exec: []string{"psql"}},
+			"go":   {Container: "tools", Exec: []string{"go"}},
+		},
+	}
+
+	var warn bytes.Buffer
+	data, err := generateCompose(config, "/workspace/", nil, &warn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "# Generated by `bridge compose generate` from bridge.yaml.\n" +
+		`# Paths are relative to the project directory; save this file there.
+services:
+  # Limits the bridge's Docker API access to listing and exec.
+  socket-proxy:
+    image: tecnativa/docker-socket-proxy:latest
+    restart: unless-stopped
+    environment:
+      CONTAINERS: "1"
+      EXEC: "1"
+      POST: "1"
+    volumes:
+      - /var/run/docker.sock:/var/run/docker.sock:ro
+
+  # Claude Code, routing commands to the sidecars through the bridge.
+  # NET_ADMIN and NET_RAW let it set up its network firewall.
+  claude:
+    image: ghcr.io/mithredate/claude-sidecar:latest
+    depends_on:
+      - socket-proxy
+      - app
+      - db
+      - front
+      - tools
+    cap_add:
+      - NET_ADMIN
+      - NET_RAW
+    environment:
+      DOCKER_HOST: tcp://socket-proxy:2375
+    volumes:
+      - .:/workspace
+      - claude-home:/home/claude/
+      - claude-config:/home/claude/.claude
+    working_dir: /workspace
+    stdin_open: true
+    tty: true
+
+  # One sidecar per logical container in bridge.yaml. Each stays up
+  # (sleep infinity) so the bridge can exec commands in it.
+  app:
+    image: php:8.3-cli
+    container_name: shop-app-1
+    command: [sleep, infinity]
+    volumes:
+      - .:/var/www/html
+    working_dir: /var/www/html
+
+  db:
+    image: alpine:3 # TODO: no image known for this container's commands
+    container_name: shop-db-1
+    command: [sleep, infinity]
+    volumes:
+      - .:/workspace
+    working_dir: /workspace
+
+  front:
+    image: node:20-alpine
+    container_name: shop-front-1
+    command: [sleep, infinity]
+    volumes:
+      - ./ui:/ui
+
+  tools:
+    image: golang:1.24-alpine
+    container_name: tools
+    command: [sleep, infinity]
+    volumes:
+      - .:/workspace
+    working_dir: /workspace
+
+volumes:
+  claude-config:
+  claude-home:
+`
+	if string(data) != want {
+		t.Errorf("compose =\n%s\nwant\n%s", data, want)
+	}
+	if !contains(warn.String(), "Not mounting /srv/shared for container 'front'") {
+		t.Errorf("expected a warning for the mapping outside the workspace, got %q", warn.String())
+	}
+	if !contains(warn.String(), "Container 'tools' is used by commands but not under containers") {
+		t.Errorf("expected a warning for the undeclared container, got %q", warn.String())
+	}
+}
+
+func TestGenerateComposeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		images  map[string]string
+		wantErr string
+	}{
+		{
+			name:    "unknown image container",
+			config:  &Config{Containers: map[string]ContainerConfig{"app": {}}},
+			images:  map[string]string{"web": "nginx"},
+			wantErr: "--image names container 'web'",
+		},
+		{
+			name:    "clashing container",
+			config:  &Config{Containers: map[string]ContainerConfig{"claude": {}}},
+			wantErr: "container 'claude' clashes with a generated service",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generateCompose(tt.config, "/workspace", tt.images, &bytes.Buffer{})
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWorkspaceRelative(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"/workspace", ".", true},
+		{"/workspace/src/", "./src", true},
+		{"/workspaces", "", false},
+		{"/other", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := workspaceRelative("/workspace", tt.path)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("workspaceRelative(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestInferWorkspace(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		want   string
+	}{
+		{
+			name:   "no mappings",
+			config: &Config{Commands: map[string]Command{"php": {Container: "php", Exec: []string{"php"}}}},
+			want:   "",
+		},
+		{
+			name: "mapping sources share a root",
+			config: &Config{
+				Containers: map[string]ContainerConfig{"php": {Paths: PathMap{"/workspace": "/var/www/html", autoPathsKey: autoPaths}}},
+				Commands: map[string]Command{
+					"npm":  {Container: "node", Exec: []string{"npm"}, Paths: PathMap{"/workspace/ui/": "/app"}},
+					"tool": {Container: "tools", Exec: []string{"tool"}, Paths: PathMap{"/srv/shared": "/shared"}},
+				},
+			},
+			want: "/workspace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inferWorkspace(tt.config); got != tt.want {
+				t.Errorf("inferWorkspace = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateComposeWorkdirNotMounted(t *testing.T) {
+	config := &Config{
+		Containers: map[string]ContainerConfig{
+			"node": {Name: "shop-node-1", Workdir: "/app", Paths: PathMap{"/workspace": "/app"}},
+			"php":  {Name: "shop-php-1", Workdir: "/var/www/html/public", Paths: PathMap{"/workspace/api": "/var/www/html"}},
+		},
+		Commands: map[string]Command{
+			"npm": {Container: "node", Exec: []string{"npm"}},
+			"php": {Container: "php", Exec: []string{"php"}},
+		},
+	}
+
+	var warn bytes.Buffer
+	data, err := generateCompose(config, "/workspaces/shop", nil, &warn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// node's mapping is outside the workspace: the project is mounted at
+	// the workspace, and the sidecar starts there rather than in /app
+	if want := "    volumes:\n      - .:/workspaces/shop\n    working_dir: /workspaces/shop\n"; !contains(string(data), want) {
+		t.Errorf("compose =\n%s\nwant node to contain %q", data, want)
+	}
+	if contains(string(data), "working_dir: /app") {
+		t.Errorf("compose =\n%s\nwant no unmounted working_dir", data)
+	}
+	for _, w := range []string{
+		"Not mounting /workspace for container 'node'",
+		"Not using workdir /app for container 'node': it is not mounted",
+	} {
+		if !contains(warn.String(), w) {
+			t.Errorf("warnings = %q, want %q", warn.String(), w)
+		}
+	}
+
+	// With the workspace inferred from the mappings, both are mounted
+	warn.Reset()
+	data, err = generateCompose(config, inferWorkspace(config), nil, &warn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !contains(string(data), "      - .:/app\n    working_dir: /app\n") || !contains(string(data), "      - ./api:/var/www/html\n    working_dir: /var/www/html/public\n") || warn.Len() != 0 {
+		t.Errorf("compose =\n%s\nwarnings = %q", data, warn.String())
+	}
+}
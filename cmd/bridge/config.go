@@ -1,111 +1,270 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
-
-	"gopkg.in/yaml.v3"
 )
 
 const (
 	exampleConfigPath = "examples/claude-bridge.yaml"
+
+	// projectConfigDir is the directory holding a project's bridge.yaml.
+	projectConfigDir = ".sidecar"
 )
 
-// getDefaultConfigPath returns the default config path based on SIDECAR_CONFIG_DIR
-// or falls back to PWD/.sidecar/bridge.yaml
+// getDefaultConfigPath returns the project config path. SIDECAR_CONFIG_DIR
+// takes precedence; otherwise the nearest .sidecar/bridge.yaml found by
+// walking up from the working directory is used (see findProjectConfig),
+// falling back to PWD/.sidecar/bridge.yaml.
 func getDefaultConfigPath() string {
-	configDir := os.Getenv("SIDECAR_CONFIG_DIR")
-	if configDir == "" {
-		// Fall back to current working directory
-		pwd, err := os.Getwd()
-		if err != nil {
-			pwd = "."
+	if configDir := os.Getenv("SIDECAR_CONFIG_DIR"); configDir != "" {
+		return configDir + "/bridge.yaml"
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		pwd = "."
+	}
+	if path, ok := findProjectConfig(pwd); ok {
+		return path
+	}
+	return filepath.Join(pwd, projectConfigDir, "bridge.yaml")
+}
+
+// findProjectConfig looks for .sidecar/bridge.yaml in dir and each of its
+// parents. The search stops at the first directory containing .git (the
+// repository root) or at the filesystem root.
+func findProjectConfig(dir string) (string, bool) {
+	dir = filepath.Clean(dir)
+	for {
+		path := filepath.Join(dir, projectConfigDir, "bridge.yaml")
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", false
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
 		}
-		configDir = pwd + "/.sidecar"
+		dir = parent
 	}
-	return configDir + "/bridge.yaml"
 }
 
 // Config represents the bridge configuration file.
 type Config struct {
-	Version          string             `yaml:"version"`
-	DefaultContainer string             `yaml:"default_container"`
-	Containers       map[string]string  `yaml:"containers"`
-	Commands         map[string]Command `yaml:"commands"`
+	Version          string                     `yaml:"version"`
+	DefaultContainer string                     `yaml:"default_container"`
+	Containers       map[string]ContainerConfig `yaml:"containers"`
+	Commands         map[string]Command         `yaml:"commands"`
+	Scopes           map[string]Scope           `yaml:"scopes"`
+	Profiles         map[string]Profile         `yaml:"profiles"`
+	Presets          map[string]PresetParams    `yaml:"presets"`
+
+	// ActiveProfile is the name of the profile applied by LoadConfig, if any.
+	ActiveProfile string `yaml:"-"`
+	// Sources lists the config files merged by LoadConfig, lowest priority first.
+	Sources []string `yaml:"-"`
+	// Origins maps top-level keys, "section.entry" names and the
+	// "section.entry.commands.name" of scope and profile commands to the
+	// file:line that defined them.
+	Origins map[string]string `yaml:"-"`
+	// Trusted reports whether LoadConfig enforced the trust policy.
+	Trusted bool `yaml:"-"`
 }
 
 // Command represents a command mapping configuration.
 type Command struct {
-	Container string            `yaml:"container"`
-	Exec      string            `yaml:"exec"`
-	Workdir   string            `yaml:"workdir"`
-	Paths     map[string]string `yaml:"paths"`
+	Container  string            `yaml:"container"`
+	Exec       []string          `yaml:"exec"`
+	Workdir    string            `yaml:"workdir"`
+	Paths      PathMap           `yaml:"paths"`
+	Env        map[string]string `yaml:"env"`
+	Routes     []Route           `yaml:"routes"`
+	SelectBy   *SelectBy         `yaml:"select_by"`
+	ArgsPrefix []string          `yaml:"args_prefix"`
+	ArgsSuffix []string          `yaml:"args_suffix"`
+	Template   string            `yaml:"template"`
+	User       string            `yaml:"user"`
+	Extends    string            `yaml:"extends"`
 }
 
-// LoadConfig reads and parses the bridge configuration file.
-// It uses BRIDGE_CONFIG env var if set, otherwise uses the default path.
-func LoadConfig(configPath string) (*Config, error) {
-	// Determine config path
-	path := configPath
-	if path == "" {
-		path = os.Getenv("BRIDGE_CONFIG")
+// LoadConfig reads, merges and parses the bridge configuration layers.
+// An explicit configPath is used on its own; otherwise the user, project,
+// project-local and BRIDGE_CONFIG layers are merged (see configLayerPaths).
+// Unknown keys and every validation problem are reported together, each
+// with its file, line and column. The profile named by profile (or
+// BRIDGE_PROFILE) is applied after validation.
+func LoadConfig(configPath, profile string) (*Config, error) {
+	// Read and merge config layers, using only approved file contents
+	// when the trust policy requires it
+	policy, err := readTrustPolicy()
+	if err != nil {
+		return nil, err
 	}
-	if path == "" {
-		path = getDefaultConfigPath()
+	var loaded *loadedConfig
+	if policy != nil {
+		loaded, err = loadTrusted(configPath, policy)
+	} else {
+		loaded, err = loadLayers(configPath)
 	}
-
-	// Read config file
-	data, err := os.ReadFile(path)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil, fmt.Errorf("config file not found: %s\nSee %s for an example configuration", path, exampleConfigPath)
-		}
-		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+		return nil, err
 	}
+	path := strings.Join(loaded.Files, ", ")
 
-	// Parse YAML
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		var yamlErr *yaml.TypeError
-		if errors.As(err, &yamlErr) {
-			return nil, fmt.Errorf("invalid YAML in %s: %s", path, yamlErr.Errors[0])
+	// Check the schema, decode and validate (including every profile)
+	diags, config := diagnoseConfig(loaded)
+	if config == nil {
+		var errs []string
+		for _, d := range diags {
+			if d.Severity == severityError {
+				errs = append(errs, d.String())
+			}
 		}
-		return nil, fmt.Errorf("invalid YAML in %s: %w", path, err)
+		if len(errs) == 1 {
+			return nil, fmt.Errorf("invalid config in %s: %s", path, errs[0])
+		}
+		return nil, fmt.Errorf("invalid config in %s: %d errors:\n  %s", path, len(errs), strings.Join(errs, "\n  "))
 	}
 
-	// Validate config
-	if err := config.Validate(); err != nil {
+	// Apply the active profile, then resolve command inheritance
+	merged, err := config.WithProfile(activeProfileName(profile))
+	if err != nil {
 		return nil, fmt.Errorf("invalid config in %s: %w", path, err)
 	}
+	if err := merged.expandExtends(); err != nil {
+		return nil, fmt.Errorf("invalid config in %s: %w", path, err)
+	}
+	merged.Trusted = policy != nil
 
-	return &config, nil
+	return merged, nil
 }
 
-// Validate checks that the config has all required fields.
+// Validate checks that the config has all required fields, returning a
+// *ValidationError listing every problem found.
+// Every profile is validated as merged onto the base, not just the active one.
 func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// problems returns every validation problem of the base config and of each
+// profile merged onto it. Problems a profile shares with the base are
+// reported once; those in commands the profile defines point at the profile.
+func (c *Config) problems() []configProblem {
+	problems := c.baseProblems()
+
+	seen := make(map[string]bool, len(problems))
+	for _, p := range problems {
+		seen[p.Message] = true
+	}
+
+	for _, name := range c.profileNames() {
+		merged, err := c.WithProfile(name)
+		if err != nil {
+			problems = append(problems, problem([]string{"profiles", name}, "%s", err))
+			continue
+		}
+		for _, p := range merged.baseProblems() {
+			if seen[p.Message] {
+				continue
+			}
+			if len(p.Path) >= 2 && p.Path[0] == "commands" {
+				if _, ok := c.Profiles[name].Commands[p.Path[1]]; ok {
+					p.Path = subPath([]string{"profiles", name}, p.Path...)
+				}
+			}
+			p.Message = fmt.Sprintf("profile '%s': %s", name, p.Message)
+			problems = append(problems, p)
+		}
+	}
+
+	return problems
+}
+
+// baseProblems checks the config's own fields without expanding profiles.
+func (c *Config) baseProblems() []configProblem {
+	var problems []configProblem
+
 	if c.Version == "" {
-		return fmt.Errorf("missing required field 'version'")
+		problems = append(problems, problem([]string{"version"}, "missing required field 'version'"))
+	} else if c.Version != "1" && c.Version != currentVersion {
+		problems = append(problems, problem([]string{"version"}, "unsupported config version '%s', expected '1' or '%s'", c.Version, currentVersion))
 	}
-	if c.Version != "1" {
-		return fmt.Errorf("unsupported config version '%s', expected '1'", c.Version)
+	// An empty commands mapping is allowed: every command then runs
+	// natively until one is added
+	if c.Commands == nil {
+		problems = append(problems, problem([]string{"commands"}, "missing required field 'commands'"))
 	}
-	if len(c.Commands) == 0 {
-		return fmt.Errorf("missing required field 'commands' (must have at least one command)")
+
+	// Validate each command (after applying extends)
+	for _, name := range sortedKeys(c.Commands) {
+		path := []string{"commands", name}
+		problems = append(problems, c.commandProblems(path, fmt.Sprintf("command '%s'", name), name, nil)...)
 	}
 
-	// Validate each command
-	for name, cmd := range c.Commands {
-		if cmd.Container == "" {
-			return fmt.Errorf("command '%s': missing required field 'container'", name)
+	// Validate each scope
+	for _, prefix := range sortedKeys(c.Scopes) {
+		scope := c.Scopes[prefix]
+		if !strings.HasPrefix(prefix, "/") {
+			problems = append(problems, problem([]string{"scopes", prefix}, "scope '%s': path prefix must be absolute", prefix))
 		}
-		if cmd.Exec == "" {
-			return fmt.Errorf("command '%s': missing required field 'exec'", name)
+		for _, name := range sortedKeys(scope.Commands) {
+			path := []string{"scopes", prefix, "commands", name}
+			label := fmt.Sprintf("scope '%s': command '%s'", prefix, name)
+			problems = append(problems, c.commandProblems(path, label, name, scope.Commands)...)
 		}
 	}
 
-	return nil
+	return problems
+}
+
+// commandProblems resolves a command's extends chain and validates the
+// result, labelling each problem with label.
+func (c *Config) commandProblems(path []string, label, name string, scope map[string]Command) []configProblem {
+	cmd, err := c.resolveExtends(name, scope)
+	if err != nil {
+		return []configProblem{problem(subPath(path, "extends"), "%s: %s", label, err)}
+	}
+	problems := validateCommand(cmd, path)
+	for i := range problems {
+		problems[i].Message = label + ": " + problems[i].Message
+	}
+	return problems
+}
+
+// validateCommand checks that a single command has all required fields.
+// path locates the command for the returned problems.
+func validateCommand(cmd Command, path []string) []configProblem {
+	var problems []configProblem
+	if cmd.Container == "" {
+		problems = append(problems, problem(path, "missing required field 'container'"))
+	}
+	if len(cmd.Exec) == 0 {
+		problems = append(problems, problem(path, "missing required field 'exec'"))
+	}
+	for i, route := range cmd.Routes {
+		if err := route.Match.Validate(); err != nil {
+			problems = append(problems, problem(subPath(path, "routes", strconv.Itoa(i), "match"), "route %d: %s", i, err))
+		}
+	}
+	if cmd.SelectBy != nil {
+		if err := cmd.SelectBy.Validate(); err != nil {
+			problems = append(problems, problem(subPath(path, "select_by"), "select_by: %s", err))
+		}
+	}
+	if err := cmd.ValidateTemplate(); err != nil {
+		problems = append(problems, problem(subPath(path, "template"), "template: %s", err))
+	}
+	return problems
 }
 
 // ResolveContainer resolves a logical container name to the actual container name.
@@ -113,8 +272,8 @@ func (c *Config) Validate() error {
 // Otherwise, returns the original name unchanged.
 func (c *Config) ResolveContainer(name string) string {
 	if c.Containers != nil {
-		if resolved, ok := c.Containers[name]; ok {
-			return resolved
+		if resolved, ok := c.Containers[name]; ok && resolved.Name != "" {
+			return resolved.Name
 		}
 	}
 	return name
@@ -133,26 +292,23 @@ func (cmd *Command) TranslatePath(path string) string {
 // Returns (translatedPath, true) if a path mapping matched (even if result is same).
 // Returns (originalPath, false) if no path mapping matched.
 func (cmd *Command) TranslatePathWithMatch(path string) (string, bool) {
-	if len(cmd.Paths) == 0 {
+	source, target, ok := cmd.pathMapping(path)
+	if !ok {
 		return path, false
 	}
+	return target + path[len(source):], true
+}
 
+// pathMapping returns the mapping that applies to path: the longest source
+// prefix of path and its target.
+func (cmd *Command) pathMapping(path string) (source, target string, ok bool) {
 	// Find the longest matching prefix for correct nested path handling
-	var longestPrefix string
-	var longestTarget string
-	for source, target := range cmd.Paths {
-		if strings.HasPrefix(path, source) {
-			if len(source) > len(longestPrefix) {
-				longestPrefix = source
-				longestTarget = target
-			}
+	for s, t := range cmd.Paths {
+		if strings.HasPrefix(path, s) && len(s) > len(source) {
+			source, target = s, t
 		}
 	}
-
-	if longestPrefix != "" {
-		return longestTarget + path[len(longestPrefix):], true
-	}
-	return path, false
+	return source, target, source != ""
 }
 
 // TranslateArgs translates all path arguments using the command's path mappings.
@@ -169,4 +325,3 @@ func (cmd *Command) TranslateArgs(args []string) []string {
 	}
 	return result
 }
-
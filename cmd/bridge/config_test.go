@@ -1,6 +1,7 @@
 package main
 
 import (
+	"path/filepath"
 	"testing"
 )
 
@@ -16,7 +17,7 @@ func TestTranslatePathWithMatch(t *testing.T) {
 			name: "path matches and translates to different path",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Paths: map[string]string{
 					"/workspaces": "/app",
 				},
@@ -29,7 +30,7 @@ func TestTranslatePathWithMatch(t *testing.T) {
 			name: "path matches with identity mapping",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Paths: map[string]string{
 					"/workspace": "/workspace",
 				},
@@ -42,7 +43,7 @@ func TestTranslatePathWithMatch(t *testing.T) {
 			name: "path does not match any mapping",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Paths: map[string]string{
 					"/workspaces": "/app",
 				},
@@ -55,7 +56,7 @@ func TestTranslatePathWithMatch(t *testing.T) {
 			name: "no paths mapping defined",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 			},
 			path:          "/some/path",
 			expectedPath:  "/some/path",
@@ -65,7 +66,7 @@ func TestTranslatePathWithMatch(t *testing.T) {
 			name: "empty paths mapping",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Paths:     map[string]string{},
 			},
 			path:          "/some/path",
@@ -76,7 +77,7 @@ func TestTranslatePathWithMatch(t *testing.T) {
 			name: "longest prefix wins (nested mappings)",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Paths: map[string]string{
 					"/workspaces":         "/app",
 					"/workspaces/project": "/project",
@@ -101,3 +102,71 @@ func TestTranslatePathWithMatch(t *testing.T) {
 	}
 }
 
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"outer/.sidecar/bridge.yaml":                    "version: \"2\"\n",
+		"outer/repo/.git/HEAD":                          "ref: refs/heads/main\n",
+		"outer/repo/src/.keep":                          "",
+		"outer/projects/api/.sidecar/bridge.yaml":       "version: \"2\"\n",
+		"outer/projects/api/internal/handlers/.keep":    "",
+		"outer/projects/web/src/components/.keep":       "",
+		"outer/projects/web/.sidecar/bridge.yaml/.keep": "",
+	})
+
+	tests := []struct {
+		name   string
+		dir    string
+		want   string
+		wantOK bool
+	}{
+		{"config in dir itself", "outer", "outer/.sidecar/bridge.yaml", true},
+		{"nearest parent wins", "outer/projects/api/internal/handlers", "outer/projects/api/.sidecar/bridge.yaml", true},
+		{"directories named bridge.yaml are skipped", "outer/projects/web/src/components", "outer/.sidecar/bridge.yaml", true},
+		{"stops at git root", "outer/repo/src", "", false},
+		{"no config up to filesystem root", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(root, tt.dir)
+			if tt.dir == "" {
+				dir = t.TempDir()
+			}
+			got, ok := findProjectConfig(dir)
+			want := ""
+			if tt.want != "" {
+				want = filepath.Join(root, tt.want)
+			}
+			if ok != tt.wantOK || got != want {
+				t.Errorf("findProjectConfig(%s) = (%q, %v), want (%q, %v)", tt.dir, got, ok, want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGetDefaultConfigPath(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".sidecar/bridge.yaml": "version: \"2\"\n",
+		"sub/dir/.keep":        "",
+	})
+	t.Chdir(filepath.Join(root, "sub", "dir"))
+
+	t.Run("discovered from subdirectory", func(t *testing.T) {
+		t.Setenv("SIDECAR_CONFIG_DIR", "")
+		want, _ := filepath.EvalSymlinks(filepath.Join(root, ".sidecar", "bridge.yaml"))
+		got, _ := filepath.EvalSymlinks(getDefaultConfigPath())
+		if got != want {
+			t.Errorf("getDefaultConfigPath() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("SIDECAR_CONFIG_DIR takes precedence", func(t *testing.T) {
+		t.Setenv("SIDECAR_CONFIG_DIR", "/etc/sidecar")
+		if got := getDefaultConfigPath(); got != "/etc/sidecar/bridge.yaml" {
+			t.Errorf("getDefaultConfigPath() = %q", got)
+		}
+	})
+}
+
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configCommand implements `bridge config <subcommand>`.
+// Returns the process exit code.
+func configCommand(args []string, configPath string) int {
+	if len(args) == 0 {
+		printConfigUsage()
+		return 1
+	}
+
+	switch args[0] {
+	case "show":
+		return configShowCommand(args[1:], configPath)
+	case "validate":
+		return configValidateCommand(args[1:], configPath)
+	case "migrate":
+		return configMigrateCommand(args[1:], configPath)
+	case "trust":
+		return configTrustCommand(args[1:], configPath)
+	case "init":
+		return configInitCommand(args[1:], configPath)
+	case "import-compose":
+		return configImportComposeCommand(args[1:], configPath)
+	case "import-devcontainer":
+		return configImportDevcontainerCommand(args[1:], configPath)
+	case "add-command":
+		return configAddCommand(args[1:], configPath)
+	case "add-container":
+		return configAddContainer(args[1:], configPath)
+	case "remove-command":
+		return configRemoveCommand(args[1:], configPath)
+	case "set":
+		return configSetCommand(args[1:], configPath)
+	case "schema":
+		if err := writeSchema(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return 1
+		}
+		return 0
+	case "help", "-h", "--help":
+		printConfigUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown config subcommand '%s'\n", args[0])
+		printConfigUsage()
+		return 1
+	}
+}
+
+// configShowCommand prints the merged configuration.
+func configShowCommand(args []string, configPath string) int {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	resolved := fs.Bool("resolved", false, "Annotate each value with the file and line that defined it")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if err := showConfig(os.Stdout, configPath, *resolved); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// configValidateCommand checks the configuration and prints every problem.
+// Returns 1 if any error (not just warnings) was found.
+func configValidateCommand(args []string, configPath string) int {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	ok, err := validateConfig(os.Stdout, configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+// validateConfig writes the diagnostics for the merged config layers followed
+// by a summary line, and reports whether the config is free of errors.
+func validateConfig(w io.Writer, configPath string) (bool, error) {
+	loaded, err := loadLayers(configPath)
+	if err != nil {
+		return false, err
+	}
+
+	diags, _ := diagnoseConfig(loaded)
+	errorCount := 0
+	for _, d := range diags {
+		fmt.Fprintln(w, d)
+		if d.Severity == severityError {
+			errorCount++
+		}
+	}
+
+	if len(diags) == 0 {
+		fmt.Fprintf(w, "%s: ok\n", strings.Join(loaded.Files, ", "))
+	} else {
+		fmt.Fprintf(w, "%d error(s), %d warning(s)\n", errorCount, len(diags)-errorCount)
+	}
+	return errorCount == 0, nil
+}
+
+// showConfig writes the merged config layers as YAML. With resolved set,
+// each top-level value and section entry is annotated with its origin.
+func showConfig(w io.Writer, configPath string, resolved bool) error {
+	loaded, err := loadLayers(configPath)
+	if err != nil {
+		return err
+	}
+	root := loaded.Root
+
+	stripComments(root)
+	if resolved {
+		fmt.Fprintln(w, "# Layers (lowest priority first):")
+		for _, p := range loaded.Files {
+			fmt.Fprintf(w, "#   %s\n", p)
+		}
+		annotateSources(root, loaded.Sources)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// stripComments removes all comments from a node tree.
+func stripComments(node *yaml.Node) {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	for _, child := range node.Content {
+		stripComments(child)
+	}
+}
+
+// annotateSources sets a line comment with the origin of each top-level key
+// and section entry.
+func annotateSources(root *yaml.Node, sources provenance) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if origin, ok := sources[key.Value]; ok {
+			key.LineComment = "from " + origin
+		}
+		if !mergedSections[key.Value] || value.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			entry := value.Content[j]
+			if origin, ok := sources[key.Value+"."+entry.Value]; ok {
+				entry.LineComment = "from " + origin
+			}
+		}
+	}
+}
+
+func printConfigUsage() {
+	fmt.Fprintf(os.Stderr, `Usage:
+  bridge config <subcommand> [flags]
+
+Subcommands:
+  show [--resolved]    Print the merged configuration (--resolved shows where each value came from)
+  validate             Check the configuration and report every error and warning with its location
+  schema               Print the JSON Schema for bridge.yaml
+  migrate [--dry-run] [--yes] [file]
+                       Rewrite a version 1 config file as version 2, showing a diff first
+  trust [--yes]        Approve the current config files after showing what changed since
+                       they were last trusted; needs a terminal, or root for --yes
+                       (enforced when /etc/bridge/trust.json exists)
+  init [--project P] [-i] [--preset P,...] [--force] [--dry-run]
+                       Write a bridge.yaml for the tools found in the running compose
+                       containers (-i picks which tools to route and where; --preset
+                       uses built-in command sets: laravel, symfony, node, go, python, rails)
+  import-compose [--project P] [--bridge-service S] [--force] [--dry-run] <file>
+                       Write a bridge.yaml for the services of a compose file, with path
+                       mappings derived from the bind mounts they share with the bridge
+  import-devcontainer [--project P] [--bridge-service S] [--force] [--dry-run] [file]
+                       Like import-compose, for the compose setup of a devcontainer.json
+                       (default .devcontainer/devcontainer.json); its service becomes the
+                       default container with workspaceFolder, remoteUser and remoteEnv
+  add-command <name> [--container C] [--workdir W] [--user U] [--extends X]
+              [--env K=V]... [--path HOST=CONTAINER]... [--force] [--] [exec...]
+                       Add a command (--force replaces an existing one)
+  add-container <name> [--name N] [--workdir W] [--user U] [--env K=V]...
+                [--path HOST=CONTAINER]... [--force]
+                       Add a container
+  remove-command <name>
+                       Remove a command
+  set <key> <value>    Set a value by dotted key, e.g. commands.php.workdir /var/www
+                       (the value is parsed as YAML, so [a, b] is a list)
+
+Editing subcommands change the project config file (or the --config file),
+keep its comments, and refuse to write a change that leaves the config invalid.
+`)
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// blankLineMarker stands in for blank lines while a config file is edited.
+// yaml.v3 keeps comments but not blank lines, so blank lines are turned
+// into marker comments before parsing and back after encoding.
+const blankLineMarker = "#bridge:blank"
+
+// configDocument is a config file parsed for editing.
+type configDocument struct {
+	path    string
+	doc     *yaml.Node
+	markers bool // Blank lines are held as marker comments
+}
+
+// readConfigDocument parses a config file for editing. ${VAR} references
+// are left unexpanded.
+func readConfigDocument(path string) (*configDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("config file not found: %s", path)
+		}
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var plain yaml.Node
+	if err := yaml.Unmarshal(data, &plain); err != nil {
+		return nil, fmt.Errorf("invalid YAML in %s: %w", path, err)
+	}
+	if len(plain.Content) == 0 {
+		plain = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if plain.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid YAML in %s: top level must be a mapping", path)
+	}
+
+	// Markers can change the meaning of block scalars containing blank
+	// lines; use them only if the file parses to the same values with them.
+	var marked yaml.Node
+	if err := yaml.Unmarshal(markBlankLines(data), &marked); err == nil && len(marked.Content) > 0 && sameYAML(data, &marked) {
+		return &configDocument{path: path, doc: &marked, markers: true}, nil
+	}
+	return &configDocument{path: path, doc: &plain}, nil
+}
+
+// root returns the document's top-level mapping.
+func (d *configDocument) root() *yaml.Node {
+	return d.doc.Content[0]
+}
+
+// bytes encodes the document, restoring blank lines.
+func (d *configDocument) bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	if !d.markers {
+		return buf.Bytes(), nil
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == blankLineMarker {
+			lines[i] = ""
+		} else {
+			lines[i] = strings.TrimRight(strings.Replace(line, blankLineMarker, "", 1), " ")
+		}
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// markBlankLines replaces blank lines with blankLineMarker comments,
+// indented like the next non-blank line so that yaml.v3 attaches them to
+// the entry that follows rather than to the end of the one before.
+func markBlankLines(data []byte) []byte {
+	lines := strings.Split(string(data), "\n")
+	indent := ""
+	for i := len(lines) - 2; i >= 0; i-- {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if strings.TrimSpace(trimmed) == "" {
+			lines[i] = indent + blankLineMarker
+		} else {
+			indent = lines[i][:len(lines[i])-len(trimmed)]
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// appendEntry adds a key/value pair to the end of a mapping. If the
+// mapping's entries are separated by blank lines, so is the new one.
+func (d *configDocument) appendEntry(mapping *yaml.Node, key string, value *yaml.Node) {
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	if d.markers {
+		for i := 2; i < len(mapping.Content); i += 2 {
+			if strings.HasPrefix(mapping.Content[i].HeadComment, blankLineMarker) {
+				keyNode.HeadComment = blankLineMarker
+				break
+			}
+		}
+	}
+	mapping.Content = append(mapping.Content, keyNode, value)
+}
+
+// section returns the named top-level mapping, creating it if needed.
+func (d *configDocument) section(name string) (*yaml.Node, error) {
+	if value := mappingValue(d.root(), name); value != nil {
+		if isNull(value) {
+			value.Kind, value.Tag, value.Value = yaml.MappingNode, "!!map", ""
+		}
+		if value.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("'%s' in %s is not a mapping", name, d.path)
+		}
+		return value, nil
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	d.appendEntry(d.root(), name, value)
+	return value, nil
+}
+
+// editConfig applies edit to a config file, validates the result together
+// with the other config layers and writes it atomically. Nothing is written
+// if the edited config has errors; warnings are printed to w.
+func editConfig(w io.Writer, configPath string, edit func(d *configDocument) error) (string, error) {
+	path, err := editTarget(configPath)
+	if err != nil {
+		return "", err
+	}
+	d, err := readConfigDocument(path)
+	if err != nil {
+		return "", err
+	}
+	if err := edit(d); err != nil {
+		return "", err
+	}
+	data, err := d.bytes()
+	if err != nil {
+		return "", err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	loaded, err := loadLayersWith(configPath, fileOverrides{abs: data})
+	if err != nil {
+		return "", err
+	}
+	diags, _ := diagnoseConfig(loaded)
+	var errs []string
+	for _, diag := range diags {
+		if diag.Severity == severityError {
+			errs = append(errs, diag.String())
+		} else {
+			fmt.Fprintln(w, diag)
+		}
+	}
+	if len(errs) > 0 {
+		return "", fmt.Errorf("not writing %s, the change leaves the config invalid:\n  %s", path, strings.Join(errs, "\n  "))
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// editTarget returns the file edited by the config editing commands: the
+// --config file if given, otherwise the project config.
+func editTarget(configPath string) (string, error) {
+	if configPath == "" {
+		return getDefaultConfigPath(), nil
+	}
+	if paths := filepath.SplitList(configPath); len(paths) > 1 {
+		return "", fmt.Errorf("--config names %d files; pass a single file to edit", len(paths))
+	}
+	return configPath, nil
+}
+
+// keyValueFlag collects repeated KEY=VALUE flags.
+type keyValueFlag map[string]string
+
+func (f keyValueFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for _, k := range sortedKeys(f) {
+		pairs = append(pairs, k+"="+f[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f keyValueFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("expected KEY=VALUE, got '%s'", value)
+	}
+	f[k] = v
+	return nil
+}
+
+// entryFlags are the flags shared by add-command and add-container.
+type entryFlags struct {
+	workdir string
+	user    string
+	env     keyValueFlag
+	paths   keyValueFlag
+	force   bool
+}
+
+func (e *entryFlags) register(fs *flag.FlagSet) {
+	e.env, e.paths = keyValueFlag{}, keyValueFlag{}
+	fs.StringVar(&e.workdir, "workdir", "", "Working directory in the container")
+	fs.StringVar(&e.user, "user", "", "User to run as")
+	fs.Var(e.env, "env", "Environment variable as KEY=VALUE (repeatable)")
+	fs.Var(e.paths, "path", "Path mapping as HOST=CONTAINER (repeatable)")
+	fs.BoolVar(&e.force, "force", false, "Replace an existing entry")
+}
+
+// addTo appends the shared fields to an entry mapping.
+func (e *entryFlags) addTo(entry *yaml.Node) {
+	if e.workdir != "" {
+		entry.Content = append(entry.Content, scalarNode("workdir"), scalarNode(e.workdir))
+	}
+	if e.user != "" {
+		entry.Content = append(entry.Content, scalarNode("user"), scalarNode(e.user))
+	}
+	if len(e.paths) > 0 {
+		entry.Content = append(entry.Content, scalarNode("paths"), stringMapNode(e.paths))
+	}
+	if len(e.env) > 0 {
+		entry.Content = append(entry.Content, scalarNode("env"), stringMapNode(e.env))
+	}
+}
+
+// parseEntryArgs splits `<name> [flags] [args...]` and parses the flags.
+func parseEntryArgs(fs *flag.FlagSet, args []string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", nil, fmt.Errorf("missing name")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return "", nil, err
+	}
+	return args[0], fs.Args(), nil
+}
+
+// configAddCommand implements `bridge config add-command`.
+func configAddCommand(args []string, configPath string) int {
+	fs := flag.NewFlagSet("config add-command", flag.ContinueOnError)
+	container := fs.String("container", "", "Container to run the command in")
+	extends := fs.String("extends", "", "Command to inherit settings from")
+	var shared entryFlags
+	shared.register(fs)
+
+	name, exec, err := parseEntryArgs(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\nUsage: bridge config add-command <name> [flags] [--] [exec...]\n", err)
+		return 1
+	}
+
+	cmd := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if *extends != "" {
+		cmd.Content = append(cmd.Content, scalarNode("extends"), scalarNode(*extends))
+	}
+	if *container != "" {
+		cmd.Content = append(cmd.Content, scalarNode("container"), scalarNode(*container))
+	}
+	if len(exec) > 0 {
+		cmd.Content = append(cmd.Content, scalarNode("exec"), argvNode(exec))
+	}
+	shared.addTo(cmd)
+
+	path, err := editConfig(os.Stderr, configPath, func(d *configDocument) error {
+		return d.putEntry("commands", "command", name, cmd, shared.force)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Added command '%s' to %s\n", name, path)
+	return 0
+}
+
+// configAddContainer implements `bridge config add-container`.
+func configAddContainer(args []string, configPath string) int {
+	fs := flag.NewFlagSet("config add-container", flag.ContinueOnError)
+	actual := fs.String("name", "", "Actual container name (default: the logical name)")
+	var shared entryFlags
+	shared.register(fs)
+
+	name, rest, err := parseEntryArgs(fs, args)
+	if err == nil && len(rest) > 0 {
+		err = fmt.Errorf("unexpected argument '%s'", rest[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\nUsage: bridge config add-container <name> [flags]\n", err)
+		return 1
+	}
+
+	container := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if *actual != "" {
+		container.Content = append(container.Content, scalarNode("name"), scalarNode(*actual))
+	}
+	shared.addTo(container)
+
+	path, err := editConfig(os.Stderr, configPath, func(d *configDocument) error {
+		return d.putEntry("containers", "container", name, container, shared.force)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Added container '%s' to %s\n", name, path)
+	return 0
+}
+
+// putEntry adds an entry to a top-level section. An existing entry is an
+// error unless replace is set, in which case its value is replaced and the
+// comments on its key are kept.
+func (d *configDocument) putEntry(section, kind, name string, value *yaml.Node, replace bool) error {
+	mapping, err := d.section(section)
+	if err != nil {
+		return err
+	}
+	if idx := mappingIndex(mapping, name); idx >= 0 {
+		if !replace {
+			return fmt.Errorf("%s '%s' already exists in %s (use --force to replace it)", kind, name, d.path)
+		}
+		mapping.Content[idx+1] = value
+		return nil
+	}
+	d.appendEntry(mapping, name, value)
+	return nil
+}
+
+// configRemoveCommand implements `bridge config remove-command`.
+func configRemoveCommand(args []string, configPath string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: bridge config remove-command <name>")
+		return 1
+	}
+	name := args[0]
+
+	path, err := editConfig(os.Stderr, configPath, func(d *configDocument) error {
+		commands := mappingValue(d.root(), "commands")
+		if commands == nil || mappingIndex(commands, name) < 0 {
+			return fmt.Errorf("command '%s' is not defined in %s", name, d.path)
+		}
+		mappingDelete(commands, name)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Removed command '%s' from %s\n", name, path)
+	return 0
+}
+
+// configSetCommand implements `bridge config set <key> <value>`.
+func configSetCommand(args []string, configPath string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: bridge config set <key> <value>")
+		return 1
+	}
+	key, raw := args[0], args[1]
+
+	value, err := parseValue(raw)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid value '%s': %s\n", raw, err)
+		return 1
+	}
+	path, err := editConfig(os.Stderr, configPath, func(d *configDocument) error {
+		return d.set(strings.Split(key, "."), value)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Set %s in %s\n", key, path)
+	return 0
+}
+
+// set assigns value at a dotted key path, creating missing mappings along
+// the way. Numeric path elements index into lists. The comments on a
+// replaced value are kept.
+func (d *configDocument) set(path []string, value *yaml.Node) error {
+	cur := d.root()
+	for i, elem := range path {
+		last := i == len(path)-1
+		where := strings.Join(path[:i], ".")
+
+		switch cur.Kind {
+		case yaml.MappingNode:
+			idx := mappingIndex(cur, elem)
+			if idx < 0 {
+				next := value
+				if !last {
+					next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				}
+				d.appendEntry(cur, elem, next)
+				cur = next
+				continue
+			}
+			if last {
+				keepComments(value, cur.Content[idx+1])
+				cur.Content[idx+1] = value
+				return nil
+			}
+			cur = cur.Content[idx+1]
+		case yaml.SequenceNode:
+			n, err := strconv.Atoi(elem)
+			if err != nil || n < 0 || n >= len(cur.Content) {
+				return fmt.Errorf("'%s' has no item %s", where, elem)
+			}
+			if last {
+				keepComments(value, cur.Content[n])
+				cur.Content[n] = value
+				return nil
+			}
+			cur = cur.Content[n]
+		default:
+			return fmt.Errorf("cannot set '%s': '%s' is not a mapping", strings.Join(path, "."), where)
+		}
+	}
+	return nil
+}
+
+// keepComments copies the comments of old onto its replacement.
+func keepComments(value, old *yaml.Node) {
+	value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+}
+
+// parseValue parses a command-line value as YAML, so lists and mappings
+// can be given in flow style (e.g. [php, artisan]).
+func parseValue(raw string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return scalarNode(""), nil
+	}
+	return doc.Content[0], nil
+}
+
+// scalarNode returns a string scalar node.
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// argvNode returns a flow-style list of strings, as used for exec.
+func argvNode(args []string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	for _, arg := range args {
+		node.Content = append(node.Content, scalarNode(arg))
+	}
+	return node
+}
+
+// stringMapNode returns a mapping of strings with sorted keys.
+func stringMapNode(m map[string]string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		node.Content = append(node.Content, scalarNode(k), scalarNode(m[k]))
+	}
+	return node
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const editableConfig = `# Project config
+version: "2"
+
+containers:
+  php:
+    name: myproject-php-1 # from docker compose
+    workdir: /var/www/html
+
+commands:
+  # PHP
+  php:
+    container: php
+    exec: [php]
+
+  artisan:
+    extends: php
+    exec: [php, artisan]
+`
+
+func TestEditConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		edit    func(d *configDocument) error
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "add command keeps comments and blank lines",
+			content: editableConfig,
+			edit: func(d *configDocument) error {
+				cmd := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				cmd.Content = append(cmd.Content, scalarNode("container"), scalarNode("php"), scalarNode("exec"), argvNode([]string{"composer"}))
+				return d.putEntry("commands", "command", "composer", cmd, false)
+			},
+			want: editableConfig + `
+  composer:
+    container: php
+    exec: [composer]
+`,
+		},
+		{
+			name:    "existing command",
+			content: editableConfig,
+			edit: func(d *configDocument) error {
+				return d.putEntry("commands", "command", "php", scalarNode("x"), false)
+			},
+			wantErr: "command 'php' already exists",
+		},
+		{
+			name:    "add section",
+			content: "version: \"2\"\ncommands:\n  npm:\n    container: app\n    exec: [npm]\n",
+			edit: func(d *configDocument) error {
+				return d.putEntry("containers", "container", "app", stringMapNode(map[string]string{"name": "app-1"}), false)
+			},
+			want: "version: \"2\"\ncommands:\n  npm:\n    container: app\n    exec: [npm]\ncontainers:\n  app:\n    name: app-1\n",
+		},
+		{
+			name:    "set keeps line comment",
+			content: editableConfig,
+			edit: func(d *configDocument) error {
+				return d.set([]string{"containers", "php", "name"}, scalarNode("other-php-1"))
+			},
+			want: strings.Replace(editableConfig, "myproject-php-1", "other-php-1", 1),
+		},
+		{
+			name:    "set creates mappings",
+			content: "version: \"2\"\ncommands:\n  php:\n    container: php\n    exec: [php]\n",
+			edit: func(d *configDocument) error {
+				return d.set([]string{"commands", "php", "env", "APP_ENV"}, scalarNode("testing"))
+			},
+			want: "version: \"2\"\ncommands:\n  php:\n    container: php\n    exec: [php]\n    env:\n      APP_ENV: testing\n",
+		},
+		{
+			name:    "set list item",
+			content: "version: \"2\"\ncommands:\n  php:\n    container: php\n    exec: [php, -d, memory_limit=128M]\n",
+			edit: func(d *configDocument) error {
+				return d.set([]string{"commands", "php", "exec", "2"}, scalarNode("memory_limit=-1"))
+			},
+			want: "version: \"2\"\ncommands:\n  php:\n    container: php\n    exec: [php, -d, memory_limit=-1]\n",
+		},
+		{
+			name:    "set below a scalar",
+			content: editableConfig,
+			edit: func(d *configDocument) error {
+				return d.set([]string{"version", "major"}, scalarNode("2"))
+			},
+			wantErr: "cannot set 'version.major': 'version' is not a mapping",
+		},
+		{
+			name:    "invalid result is not written",
+			content: editableConfig,
+			edit: func(d *configDocument) error {
+				mappingDelete(mappingValue(d.root(), "commands"), "php")
+				return nil
+			},
+			wantErr: "not writing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.content)
+			_, err := editConfig(&bytes.Buffer{}, path, tt.edit)
+
+			data, readErr := os.ReadFile(path)
+			if readErr != nil {
+				t.Fatalf("read: %v", readErr)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if string(data) != tt.content {
+					t.Errorf("file changed despite error:\n%s", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("file =\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadConfigDocumentBlockScalar(t *testing.T) {
+	content := "version: \"2\"\n\ncommands:\n  php:\n    container: php\n    exec: [php]\n    env:\n      NOTE: |\n        first\n\n        second\n"
+	d, err := readConfigDocument(writeConfig(t, content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.markers {
+		t.Error("expected blank line markers to be skipped for a block scalar with blank lines")
+	}
+	note := mappingValue(mappingValue(mappingValue(mappingValue(d.root(), "commands"), "php"), "env"), "NOTE")
+	if note == nil || note.Value != "first\n\nsecond\n" {
+		t.Errorf("NOTE = %v", note)
+	}
+}
+
+func TestEditTarget(t *testing.T) {
+	if _, err := editTarget("a.yaml:b.yaml"); err == nil || !contains(err.Error(), "pass a single file") {
+		t.Errorf("expected error for several files, got %v", err)
+	}
+	if got, err := editTarget("a.yaml"); err != nil || got != "a.yaml" {
+		t.Errorf("editTarget(a.yaml) = %q, %v", got, err)
+	}
+}
+
+func TestKeyValueFlag(t *testing.T) {
+	f := keyValueFlag{}
+	for _, v := range []string{"B=2", "A=x=y"} {
+		if err := f.Set(v); err != nil {
+			t.Fatalf("Set(%q): %v", v, err)
+		}
+	}
+	if f.String() != "A=x=y,B=2" {
+		t.Errorf("String() = %q", f.String())
+	}
+	if err := f.Set("novalue"); err == nil {
+		t.Error("expected error for missing '='")
+	}
+}
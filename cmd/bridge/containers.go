@@ -0,0 +1,43 @@
+package main
+
+// ContainerConfig maps a logical container name to the actual container and
+// holds defaults for every command routed to it. Version 1 configs may give
+// just the container name; it is upgraded to a mapping with name set.
+type ContainerConfig struct {
+	Name    string            `yaml:"name"`
+	Workdir string            `yaml:"workdir"`
+	Paths   PathMap           `yaml:"paths"`
+	Env     map[string]string `yaml:"env"`
+	User    string            `yaml:"user"`
+}
+
+// ApplyContainerDefaults fills the command's unset workdir, paths and user
+// from its container's defaults. Container env entries are used for
+// variables the command does not set itself.
+func (c *Config) ApplyContainerDefaults(cmd Command) Command {
+	defaults, ok := c.Containers[cmd.Container]
+	if !ok {
+		return cmd
+	}
+
+	if cmd.Workdir == "" {
+		cmd.Workdir = defaults.Workdir
+	}
+	if cmd.Paths == nil {
+		cmd.Paths = defaults.Paths
+	}
+	if cmd.User == "" {
+		cmd.User = defaults.User
+	}
+	if len(defaults.Env) > 0 {
+		env := make(map[string]string, len(defaults.Env)+len(cmd.Env))
+		for k, v := range defaults.Env {
+			env[k] = v
+		}
+		for k, v := range cmd.Env {
+			env[k] = v
+		}
+		cmd.Env = env
+	}
+	return cmd
+}
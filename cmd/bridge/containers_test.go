@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestContainerConfigUnmarshal(t *testing.T) {
+	var config Config
+	err := yaml.Unmarshal([]byte(`containers:
+  node:
+    name: myproject-node-1
+  php:
+    name: myproject-php-1
+    workdir: /var/www/html
+    user: www-data
+    paths:
+      /workspace: /var/www/html
+    env:
+      APP_ENV: local
+`), &config)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if config.ResolveContainer("node") != "myproject-node-1" {
+		t.Errorf("node resolved to %q", config.ResolveContainer("node"))
+	}
+	php := config.Containers["php"]
+	if php.Name != "myproject-php-1" || php.Workdir != "/var/www/html" || php.User != "www-data" {
+		t.Errorf("php container = %+v", php)
+	}
+	if php.Paths["/workspace"] != "/var/www/html" || php.Env["APP_ENV"] != "local" {
+		t.Errorf("php container maps = %+v", php)
+	}
+}
+
+func TestResolveContainerWithoutName(t *testing.T) {
+	config := &Config{Containers: map[string]ContainerConfig{"php": {Workdir: "/app"}}}
+	if got := config.ResolveContainer("php"); got != "php" {
+		t.Errorf("ResolveContainer() = %q, want logical name when no name is set", got)
+	}
+}
+
+func TestApplyContainerDefaults(t *testing.T) {
+	config := &Config{
+		Containers: map[string]ContainerConfig{
+			"php": {
+				Name:    "myproject-php-1",
+				Workdir: "/var/www/html",
+				Paths:   map[string]string{"/workspace": "/var/www/html"},
+				Env:     map[string]string{"APP_ENV": "local", "XDEBUG_MODE": "off"},
+				User:    "www-data",
+			},
+		},
+	}
+
+	cmd := config.ApplyContainerDefaults(Command{Container: "php", Exec: []string{"php"}})
+	if cmd.Workdir != "/var/www/html" || cmd.User != "www-data" || cmd.Paths["/workspace"] != "/var/www/html" {
+		t.Errorf("defaults not applied: %+v", cmd)
+	}
+
+	cmd = config.ApplyContainerDefaults(Command{
+		Container: "php",
+		Exec:      []string{"php"},
+		Workdir:   "/srv",
+		Paths:     map[string]string{"/workspace": "/srv"},
+		Env:       map[string]string{"XDEBUG_MODE": "coverage"},
+		User:      "root",
+	})
+	if cmd.Workdir != "/srv" || cmd.User != "root" || cmd.Paths["/workspace"] != "/srv" {
+		t.Errorf("command values should win: %+v", cmd)
+	}
+	if cmd.Env["APP_ENV"] != "local" || cmd.Env["XDEBUG_MODE"] != "coverage" {
+		t.Errorf("env not merged: %v", cmd.Env)
+	}
+
+	cmd = config.ApplyContainerDefaults(Command{Container: "node", Exec: []string{"node"}})
+	if cmd.Workdir != "" || cmd.Paths != nil {
+		t.Errorf("unknown container should leave command unchanged: %+v", cmd)
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// defaultDevcontainerPath is where dev container tooling looks first.
+const defaultDevcontainerPath = ".devcontainer/devcontainer.json"
+
+// devcontainer is the part of a compose-based devcontainer.json that
+// describes the container commands run in.
+type devcontainer struct {
+	DockerComposeFile stringList         `json:"dockerComposeFile"`
+	Service           string             `json:"service"`
+	WorkspaceFolder   string             `json:"workspaceFolder"`
+	RemoteUser        string             `json:"remoteUser"`
+	RemoteEnv         map[string]*string `json:"remoteEnv"`
+}
+
+// stringList is a JSON string or array of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = stringList{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("expected a string or a list of strings")
+	}
+	*l = list
+	return nil
+}
+
+// readDevcontainer parses a devcontainer.json file, which may contain
+// comments and trailing commas.
+func readDevcontainer(path string) (*devcontainer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var dc devcontainer
+	if err := json.Unmarshal(stripJSONC(data), &dc); err != nil {
+		return nil, fmt.Errorf("invalid JSON in %s: %w", path, err)
+	}
+	if len(dc.DockerComposeFile) == 0 {
+		return nil, fmt.Errorf("%s has no dockerComposeFile; only compose-based dev containers can be imported", path)
+	}
+	if dc.Service == "" {
+		return nil, fmt.Errorf("%s has no service", path)
+	}
+	return &dc, nil
+}
+
+// stripJSONC removes // and /* */ comments and trailing commas from JSON
+// with comments, leaving string contents alone.
+func stripJSONC(data []byte) []byte {
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+		case c == ']' || c == '}':
+			// Drop a trailing comma before the closing bracket
+			trimmed := bytes.TrimRight(out.Bytes(), " \t\r\n")
+			if len(trimmed) > 0 && trimmed[len(trimmed)-1] == ',' {
+				rest := out.Bytes()[len(trimmed):]
+				kept := append([]byte(nil), rest...)
+				out.Truncate(len(trimmed) - 1)
+				out.Write(kept)
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+// mergeComposeFiles combines compose files the way docker compose does for
+// repeated -f flags: later files add services and override single values,
+// while volumes accumulate.
+func mergeComposeFiles(files []*composeFile) *composeFile {
+	merged := &composeFile{}
+	index := make(map[string]int)
+	for _, f := range files {
+		if f.Name != "" {
+			merged.Name = f.Name
+		}
+		for _, s := range f.Services {
+			i, ok := index[s.Name]
+			if !ok {
+				index[s.Name] = len(merged.Services)
+				merged.Services = append(merged.Services, s)
+				continue
+			}
+			m := &merged.Services[i]
+			if s.Image != "" {
+				m.Image = s.Image
+			}
+			if s.ContainerName != "" {
+				m.ContainerName = s.ContainerName
+			}
+			if s.WorkingDir != "" {
+				m.WorkingDir = s.WorkingDir
+			}
+			m.Volumes = append(m.Volumes, s.Volumes...)
+		}
+	}
+	return merged
+}
+
+// devcontainerEnvVar matches ${...} references in devcontainer.json values.
+var devcontainerEnvVar = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// translateRemoteEnv converts a remoteEnv value to bridge.yaml syntax.
+// ${localEnv:VAR} and ${localEnv:VAR:default} become ${VAR} and
+// ${VAR:-default}; other variables (containerEnv, localWorkspaceFolder, ...)
+// have no bridge equivalent and are reported as unsupported.
+func translateRemoteEnv(value string) (string, error) {
+	var out strings.Builder
+	last := 0
+	for _, m := range devcontainerEnvVar.FindAllStringSubmatchIndex(value, -1) {
+		out.WriteString(strings.ReplaceAll(value[last:m[0]], "$", "$$"))
+		ref := value[m[2]:m[3]]
+		name, ok := strings.CutPrefix(ref, "localEnv:")
+		if !ok {
+			return "", fmt.Errorf("cannot translate '${%s}'", ref)
+		}
+		if name, def, hasDefault := strings.Cut(name, ":"); hasDefault {
+			out.WriteString("${" + name + ":-" + def + "}")
+		} else {
+			out.WriteString("${" + name + "}")
+		}
+		last = m[1]
+	}
+	out.WriteString(strings.ReplaceAll(value[last:], "$", "$$"))
+	return out.String(), nil
+}
+
+// devcontainerProject returns the compose project name dev container
+// tooling uses: <workspace>_devcontainer for compose files kept in
+// .devcontainer, otherwise what docker compose would use.
+func devcontainerProject(file *composeFile, explicit, composePath string) string {
+	if explicit == "" && file.Name == "" && os.Getenv("COMPOSE_PROJECT_NAME") == "" {
+		if abs, err := filepath.Abs(composePath); err == nil && filepath.Base(filepath.Dir(abs)) == ".devcontainer" {
+			workspace := filepath.Base(filepath.Dir(filepath.Dir(abs)))
+			return file.projectName(workspace+"_devcontainer", composePath)
+		}
+	}
+	return file.projectName(explicit, composePath)
+}
+
+// configImportDevcontainerCommand implements
+// `bridge config import-devcontainer`.
+func configImportDevcontainerCommand(args []string, configPath string) int {
+	fs := flag.NewFlagSet("config import-devcontainer", flag.ContinueOnError)
+	project := fs.String("project", "", "Compose project name (default: as dev container tooling derives it)")
+	bridgeName := fs.String("bridge-service", "", "Service the bridge runs in (default: the claude-sidecar service)")
+	force := fs.Bool("force", false, "Overwrite an existing config file")
+	dryRun := fs.Bool("dry-run", false, "Print the config instead of writing it")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "Usage: bridge config import-devcontainer [flags] [devcontainer.json]")
+		return 1
+	}
+	dcPath := defaultDevcontainerPath
+	if fs.NArg() == 1 {
+		dcPath = fs.Arg(0)
+	}
+
+	path, err := editTarget(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if _, err := os.Stat(path); err == nil && !*force && !*dryRun {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use --force to overwrite it)\n", path)
+		return 1
+	}
+
+	data, plan, err := importDevcontainer(dcPath, *project, *bridgeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if *dryRun {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := writeNewConfig(path, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s with %d command(s) in %d container(s)\n", path, len(plan.Commands), len(plan.Containers))
+	return 0
+}
+
+// importDevcontainer generates bridge.yaml from a devcontainer.json and the
+// compose files it names. The dev container's service becomes the default
+// container, with workspaceFolder, remoteUser and remoteEnv as its workdir,
+// user and env.
+func importDevcontainer(dcPath, project, bridgeName string) ([]byte, initPlan, error) {
+	dc, err := readDevcontainer(dcPath)
+	if err != nil {
+		return nil, initPlan{}, err
+	}
+
+	var files []*composeFile
+	var composePaths []string
+	for _, f := range dc.DockerComposeFile {
+		composePath := filepath.Join(filepath.Dir(dcPath), f)
+		file, err := readComposeFile(composePath)
+		if err != nil {
+			return nil, initPlan{}, err
+		}
+		files = append(files, file)
+		composePaths = append(composePaths, composePath)
+	}
+	file := mergeComposeFiles(files)
+
+	found := false
+	for _, s := range file.Services {
+		found = found || s.Name == dc.Service
+	}
+	if !found {
+		return nil, initPlan{}, fmt.Errorf("%s: service '%s' is not defined in %s", dcPath, dc.Service, strings.Join(composePaths, ", "))
+	}
+	bridge, err := file.bridgeService(bridgeName)
+	if err != nil {
+		return nil, initPlan{}, fmt.Errorf("%s: %w", strings.Join(composePaths, ", "), err)
+	}
+	if bridge == nil {
+		fmt.Fprintf(os.Stderr, "No claude-sidecar service found in %s; not deriving path mappings (use --bridge-service)\n", strings.Join(composePaths, ", "))
+	}
+
+	plan := buildComposePlan(file, devcontainerProject(file, project, composePaths[0]), bridge, dc.Service)
+	for i := range plan.Containers {
+		c := &plan.Containers[i]
+		if c.Info.Service != dc.Service {
+			continue
+		}
+		plan.Main = c.Logical
+		if dc.WorkspaceFolder != "" {
+			c.Info.WorkingDir = dc.WorkspaceFolder
+		}
+		c.User = dc.RemoteUser
+		for _, name := range sortedKeys(dc.RemoteEnv) {
+			value := dc.RemoteEnv[name]
+			if value == nil {
+				continue
+			}
+			translated, err := translateRemoteEnv(*value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping remoteEnv.%s: %s\n", name, err)
+				continue
+			}
+			if c.Env == nil {
+				c.Env = make(map[string]string)
+			}
+			c.Env[name] = translated
+		}
+	}
+
+	// The dev container is where the project's tools are expected, so it
+	// runs every tool it provides
+	routed := false
+	for i := range plan.Commands {
+		cmd := &plan.Commands[i]
+		if slices.Contains(cmd.Candidates, plan.Main) {
+			cmd.Container = plan.Main
+			routed = true
+		}
+	}
+	if !routed {
+		fmt.Fprintf(os.Stderr, "Service '%s' provides no known commands; its workdir, user and env apply to commands you route to it with bridge config add-command --container %s\n", dc.Service, plan.Main)
+	}
+
+	// The service is kept even without commands, e.g. when it is built
+	// from a Dockerfile whose tools are unknown
+	plan.prune()
+
+	header := "# Generated by `bridge config import-devcontainer` from " + filepath.Base(dcPath) + "."
+	data, err := renderPlan(plan, header, "# Commands picked from each service's image or name.")
+	return data, plan, err
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]any
+	}{
+		{
+			name:  "line comments",
+			input: "{\n  // the service\n  \"service\": \"app\" // trailing\n}",
+			want:  map[string]any{"service": "app"},
+		},
+		{
+			name:  "block comments",
+			input: `{ /* a */ "service": /* b */ "app" }`,
+			want:  map[string]any{"service": "app"},
+		},
+		{
+			name:  "trailing commas",
+			input: "{\"a\": [1, 2,],\n \"b\": {\"c\": true,},\n}",
+			want:  map[string]any{"a": []any{1.0, 2.0}, "b": map[string]any{"c": true}},
+		},
+		{
+			name:  "comment markers in strings",
+			input: `{"url": "http://example.com/*x*/", "s": "a,}", "q": "\"//\""}`,
+			want:  map[string]any{"url": "http://example.com/*x*/", "s": "a,}", "q": `"//"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			if err := json.Unmarshal(stripJSONC([]byte(tt.input)), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stripJSONC = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateRemoteEnv(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{value: "testing", want: "testing"},
+		{value: "${localEnv:HOME}/.cache", want: "${HOME}/.cache"},
+		{value: "${localEnv:TOKEN:none}", want: "${TOKEN:-none}"},
+		{value: "price $5", want: "price $$5"},
+		{value: "${containerEnv:PATH}:/opt/bin", wantErr: "cannot translate '${containerEnv:PATH}'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := translateRemoteEnv(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("translateRemoteEnv(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportDevcontainer(t *testing.T) {
+	t.Setenv("COMPOSE_PROJECT_NAME", "")
+	dir := filepath.Join(t.TempDir(), "shop")
+	writeFiles(t, dir, map[string]string{
+		".devcontainer/devcontainer.json": `{
+  // Compose-based dev container
+  "name": "Shop",
+  "dockerComposeFile": ["../compose.yaml", "compose.extend.yaml"],
+  "service": "workspace",
+  "workspaceFolder": "/workspaces/shop",
+  "remoteUser": "vscode",
+  "remoteEnv": {
+    "APP_ENV": "local",
+    "GH_TOKEN": "${localEnv:GH_TOKEN}",
+    "PATH": "${containerEnv:PATH}:/opt/bin",
+    "UNSET": null,
+  },
+}`,
+		"compose.yaml": `services:
+  claude:
+    image: ghcr.io/mithredate/claude-sidecar:latest
+    volumes:
+      - .:/workspace
+  workspace:
+    image: mcr.microsoft.com/devcontainers/base:ubuntu
+  php:
+    image: php:8.3-fpm
+    volumes:
+      - .:/var/www/html
+`,
+		".devcontainer/compose.extend.yaml": `services:
+  workspace:
+    volumes:
+      - ..:/workspaces/shop
+`,
+	})
+
+	data, _, err := importDevcontainer(filepath.Join(dir, ".devcontainer/devcontainer.json"), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "# Generated by `bridge config import-devcontainer` from devcontainer.json.\n" +
+		`# See examples/claude-bridge.yaml for everything bridge.yaml can do.
+version: "2"
+
+# Logical container names mapped to the actual container names.
+# Commands run in their container's working directory.
+containers:
+  php:
+    name: shop-php-1 # image: php:8.3-fpm
+    paths:
+      /workspace: /var/www/html
+  workspace:
+    name: shop-workspace-1 # image: mcr.microsoft.com/devcontainers/base:ubuntu
+    workdir: /workspaces/shop
+    paths:
+      /workspace: /workspaces/shop
+    user: vscode
+    env:
+      APP_ENV: local
+      GH_TOKEN: ${GH_TOKEN}
+
+# Commands picked from each service's image or name.
+commands:
+  php:
+    container: php
+    exec: [php]
+  composer:
+    container: php
+    exec: [composer]
+`
+	if string(data) != want {
+		t.Errorf("config =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestImportDevcontainerRoutesToService(t *testing.T) {
+	t.Setenv("COMPOSE_PROJECT_NAME", "")
+	dir := filepath.Join(t.TempDir(), "shop")
+	writeFiles(t, dir, map[string]string{
+		".devcontainer/devcontainer.json": `{"dockerComposeFile": "../compose.yaml", "service": "app", "remoteUser": "vscode"}`,
+		"compose.yaml": `services:
+  claude:
+    image: ghcr.io/mithredate/claude-sidecar:latest
+    volumes:
+      - .:/workspace
+  app:
+    image: mcr.microsoft.com/devcontainers/php:8.3
+  php:
+    image: php:8.3-fpm
+    volumes:
+      - .:/var/www/html
+`,
+	})
+
+	_, plan, err := importDevcontainer(filepath.Join(dir, ".devcontainer/devcontainer.json"), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The dev container's service runs the tools it provides, even where
+	// another service provides them too
+	for _, cmd := range plan.Commands {
+		if cmd.Container != "app" {
+			t.Errorf("command %s runs in %s, want app", cmd.Tool, cmd.Container)
+		}
+	}
+	if len(plan.Containers) != 1 || plan.Containers[0].Logical != "app" || plan.Containers[0].User != "vscode" {
+		t.Errorf("containers = %+v, want only app with user vscode", plan.Containers)
+	}
+}
+
+func TestImportDevcontainerBuiltService(t *testing.T) {
+	t.Setenv("COMPOSE_PROJECT_NAME", "")
+	dir := filepath.Join(t.TempDir(), "shop")
+	writeFiles(t, dir, map[string]string{
+		".devcontainer/devcontainer.json": `{"dockerComposeFile": "compose.yaml", "service": "dev", "workspaceFolder": "/workspaces/shop", "remoteUser": "vscode"}`,
+		".devcontainer/compose.yaml": `services:
+  dev:
+    build:
+      context: .
+      dockerfile: Dockerfile
+`,
+	})
+
+	data, plan, err := importDevcontainer(filepath.Join(dir, ".devcontainer/devcontainer.json"), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "# Generated by `bridge config import-devcontainer` from devcontainer.json.\n" +
+		`# See examples/claude-bridge.yaml for everything bridge.yaml can do.
+version: "2"
+
+# Logical container names mapped to the actual container names.
+# Commands run in their container's working directory.
+containers:
+  dev:
+    name: shop_devcontainer-dev-1
+    workdir: /workspaces/shop
+    user: vscode
+
+# Commands picked from each service's image or name.
+# None found; add them with bridge config add-command.
+commands: {}
+`
+	if string(data) != want {
+		t.Errorf("config =\n%s\nwant\n%s", data, want)
+	}
+	if plan.Main != "dev" || len(plan.Commands) != 0 {
+		t.Errorf("main = %q, commands = %+v, want dev and none", plan.Main, plan.Commands)
+	}
+}
+
+func TestImportDevcontainerErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"image.json":   `{"image": "mcr.microsoft.com/devcontainers/go:1"}`,
+		"missing.json": `{"dockerComposeFile": "compose.yaml", "service": "app"}`,
+		"compose.yaml": "services:\n  web:\n    image: php:8.3\n",
+	})
+
+	tests := []struct {
+		file    string
+		wantErr string
+	}{
+		{file: "image.json", wantErr: "only compose-based dev containers can be imported"},
+		{file: "missing.json", wantErr: "service 'app' is not defined"},
+		{file: "absent.json", wantErr: "failed to read"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, _, err := importDevcontainer(filepath.Join(dir, tt.file), "", "")
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
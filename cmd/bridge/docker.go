@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Labels docker compose sets on the containers it creates.
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+// runDocker runs the docker CLI and returns its standard output. It is a
+// variable so tests can substitute canned responses.
+var runDocker = func(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("docker %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("docker %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// containerInfo is what the bridge needs to know about a running container.
+type containerInfo struct {
+	ID         string
+	Name       string
+	Image      string
+	WorkingDir string
+	User       string
+	Project    string // Compose project, if started by docker compose
+	Service    string // Compose service, if started by docker compose
+	Status     string // created, running, exited, ...
+	Mounts     []containerMount
+}
+
+// containerMount is a volume or bind mount of a container.
+type containerMount struct {
+	Type        string
+	Source      string
+	Destination string
+}
+
+// inspectResult is the subset of `docker inspect` output that is used.
+type inspectResult struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image      string            `json:"Image"`
+		WorkingDir string            `json:"WorkingDir"`
+		User       string            `json:"User"`
+		Labels     map[string]string `json:"Labels"`
+	} `json:"Config"`
+	State struct {
+		Status string `json:"Status"`
+	} `json:"State"`
+	Mounts []containerMount `json:"Mounts"`
+}
+
+// inspectContainers returns details for the named containers, in order.
+func inspectContainers(names ...string) ([]containerInfo, error) {
+	out, err := runDocker(append([]string{"inspect", "--type", "container"}, names...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseInspect(out)
+}
+
+// parseInspect decodes `docker inspect` output.
+func parseInspect(data []byte) ([]containerInfo, error) {
+	var results []inspectResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse docker inspect output: %w", err)
+	}
+
+	containers := make([]containerInfo, 0, len(results))
+	for _, r := range results {
+		containers = append(containers, containerInfo{
+			ID:         r.ID,
+			Name:       strings.TrimPrefix(r.Name, "/"),
+			Image:      r.Config.Image,
+			WorkingDir: r.Config.WorkingDir,
+			User:       r.Config.User,
+			Project:    r.Config.Labels[composeProjectLabel],
+			Service:    r.Config.Labels[composeServiceLabel],
+			Status:     r.State.Status,
+			Mounts:     r.Mounts,
+		})
+	}
+	return containers, nil
+}
+
+// listContainers returns the running containers, limited to a compose
+// project if one is given.
+func listContainers(project string) ([]containerInfo, error) {
+	args := []string{"ps", "--quiet", "--no-trunc"}
+	if project != "" {
+		args = append(args, "--filter", "label="+composeProjectLabel+"="+project)
+	}
+	out, err := runDocker(args...)
+	if err != nil {
+		return nil, err
+	}
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return inspectContainers(ids...)
+}
+
+// selfContainer returns the container the bridge is running in. Docker sets
+// a container's hostname to its short ID unless the hostname is configured.
+func selfContainer() (containerInfo, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return containerInfo{}, err
+	}
+	containers, err := inspectContainers(hostname)
+	if err != nil {
+		return containerInfo{}, err
+	}
+	if len(containers) == 0 {
+		return containerInfo{}, errors.New("not running in a container")
+	}
+	return containers[0], nil
+}
+
+// probeTools reports which of the given tools are on the container's PATH,
+// using `command -v` in a POSIX shell.
+func probeTools(container string, tools []string) ([]string, error) {
+	return probeToolsAs(container, "", "", tools)
+}
+
+// probeToolsAs is probeTools run in workdir as user, so that relative paths
+// such as vendor/bin/phpunit resolve as they would for a routed command.
+// Empty workdir and user use the container's defaults.
+func probeToolsAs(container, workdir, user string, tools []string) ([]string, error) {
+	script := `for t in "$@"; do command -v "$t" >/dev/null 2>&1 && echo "$t"; done; true`
+	args := []string{"exec"}
+	if workdir != "" {
+		args = append(args, "-w", workdir)
+	}
+	if user != "" {
+		args = append(args, "-u", user)
+	}
+	args = append(append(args, container, "sh", "-c", script, "sh"), tools...)
+	out, err := runDocker(args...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeDocker replaces runDocker for the duration of a test. Responses are
+// keyed by the space-joined docker arguments; a missing key is an error.
+func fakeDocker(t *testing.T, responses map[string]string) *[]string {
+	t.Helper()
+	var calls []string
+	orig := runDocker
+	runDocker = func(args ...string) ([]byte, error) {
+		key := strings.Join(args, " ")
+		calls = append(calls, key)
+		if out, ok := responses[key]; ok {
+			return []byte(out), nil
+		}
+		return nil, errors.New("docker " + args[0] + ": no such object")
+	}
+	t.Cleanup(func() { runDocker = orig })
+	return &calls
+}
+
+const inspectPHP = `[{
+  "Id": "abc123",
+  "Name": "/myproject-php-1",
+  "Config": {
+    "Image": "php:8.3-fpm",
+    "WorkingDir": "/var/www/html",
+    "User": "www-data",
+    "Labels": {
+      "com.docker.compose.project": "myproject",
+      "com.docker.compose.service": "php"
+    }
+  },
+  "Mounts": [
+    {"Type": "bind", "Source": "/home/dev/myproject", "Destination": "/var/www/html", "Mode": "rw"}
+  ]
+}]`
+
+func TestParseInspect(t *testing.T) {
+	containers, err := parseInspect([]byte(inspectPHP))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []containerInfo{{
+		ID:         "abc123",
+		Name:       "myproject-php-1",
+		Image:      "php:8.3-fpm",
+		WorkingDir: "/var/www/html",
+		User:       "www-data",
+		Project:    "myproject",
+		Service:    "php",
+		Mounts:     []containerMount{{Type: "bind", Source: "/home/dev/myproject", Destination: "/var/www/html"}},
+	}}
+	if !reflect.DeepEqual(containers, want) {
+		t.Errorf("parseInspect =\n%+v\nwant\n%+v", containers, want)
+	}
+
+	if _, err := parseInspect([]byte("not json")); err == nil || !contains(err.Error(), "failed to parse docker inspect output") {
+		t.Errorf("expected parse error, got %v", err)
+	}
+}
+
+func TestListContainers(t *testing.T) {
+	calls := fakeDocker(t, map[string]string{
+		"ps --quiet --no-trunc --filter label=com.docker.compose.project=myproject": "abc123\n",
+		"inspect --type container abc123":                                           inspectPHP,
+	})
+
+	containers, err := listContainers("myproject")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(containers) != 1 || containers[0].Name != "myproject-php-1" {
+		t.Errorf("containers = %+v", containers)
+	}
+	if len(*calls) != 2 {
+		t.Errorf("calls = %q", *calls)
+	}
+
+	containers, err = listContainers("other")
+	if err == nil {
+		t.Errorf("expected error for unknown project, got %+v", containers)
+	}
+}
+
+func TestProbeTools(t *testing.T) {
+	script := `for t in "$@"; do command -v "$t" >/dev/null 2>&1 && echo "$t"; done; true`
+	fakeDocker(t, map[string]string{
+		"exec php sh -c " + script + " sh php composer node": "php\ncomposer\n",
+	})
+
+	tools, err := probeTools("php", []string{"php", "composer", "node"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(tools, []string{"php", "composer"}) {
+		t.Errorf("tools = %q", tools)
+	}
+}
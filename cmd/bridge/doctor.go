@@ -0,0 +1,295 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Outcomes of a doctor check.
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// checkResult is the outcome of one `bridge doctor` check.
+type checkResult struct {
+	Status  string
+	Subject string
+	Message string
+	Hint    string // How to fix a warning or failure
+}
+
+// doctor collects check results.
+type doctor struct {
+	results []checkResult
+}
+
+func (d *doctor) add(status, subject, hint, format string, args ...any) {
+	d.results = append(d.results, checkResult{Status: status, Subject: subject, Message: fmt.Sprintf(format, args...), Hint: hint})
+}
+
+// failed reports whether any check failed.
+func (d *doctor) failed() bool {
+	for _, r := range d.results {
+		if r.Status == checkFail {
+			return true
+		}
+	}
+	return false
+}
+
+// write prints the results, one per line, with hints below warnings and
+// failures.
+func (d *doctor) write(w io.Writer) {
+	for _, r := range d.results {
+		fmt.Fprintf(w, "%-4s  %s: %s\n", strings.ToUpper(r.Status), r.Subject, r.Message)
+		if r.Hint != "" && r.Status != checkPass {
+			fmt.Fprintf(w, "      hint: %s\n", r.Hint)
+		}
+	}
+}
+
+// doctorCommand implements `bridge doctor`. It loads the config itself so
+// that a config that fails to load is reported as a failed check.
+func doctorCommand(args []string, configPath, profile string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	wrappers := fs.String("wrappers", "", "Wrapper directory (default: the directory of the dispatcher on PATH)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	d := &doctor{}
+	config, err := LoadConfig(configPath, profile)
+	if err != nil {
+		d.add(checkFail, "config", "run 'bridge config validate' for details", "%s", err)
+	} else {
+		d.add(checkPass, "config", "", "loaded %s (%d commands)", strings.Join(config.Sources, ", "), len(config.Commands))
+		if d.checkDocker() {
+			d.checkRoutes(config)
+		}
+		d.checkWrappers(config, *wrappers)
+	}
+
+	d.write(os.Stdout)
+	if d.failed() {
+		return 1
+	}
+	return 0
+}
+
+// checkDocker checks that the Docker API is reachable and that the
+// container list endpoint is allowed. Later checks are skipped when it
+// fails.
+func (d *doctor) checkDocker() bool {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = "the default socket"
+	}
+	out, err := runDocker("version", "--format", "{{.Server.Version}}")
+	if err != nil {
+		hint := "set DOCKER_HOST to the socket proxy, e.g. tcp://socket-proxy:2375"
+		if os.Getenv("DOCKER_HOST") != "" {
+			hint = "check that the socket proxy is running and on the same network as this container"
+		}
+		d.add(checkFail, "docker", hint, "cannot reach the Docker API at %s: %s", host, err)
+		return false
+	}
+	d.add(checkPass, "docker", "", "Docker %s reachable at %s", strings.TrimSpace(string(out)), host)
+
+	if _, err := runDocker("ps", "--quiet"); err != nil {
+		d.add(checkFail, "docker", proxyHint(err, "CONTAINERS=1"), "listing containers failed: %s", err)
+		return false
+	}
+	return true
+}
+
+// proxyHint suggests the socket proxy setting to enable when err looks like
+// a refusal by the proxy.
+func proxyHint(err error, settings string) string {
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "403") || strings.Contains(msg, "forbidden") {
+		return "the socket proxy blocks this endpoint; set " + settings + " on it"
+	}
+	return ""
+}
+
+// checkRoutes checks that every container is running, that each command's
+// executable exists in it, and that mapped paths and workdirs exist there.
+func (d *doctor) checkRoutes(config *Config) {
+	entries := routingTable(config, currentDir())
+	checkRoutes(entries)
+
+	reported := make(map[string]bool)
+	for _, e := range entries {
+		for _, warning := range e.Warnings {
+			d.add(checkWarn, "command '"+e.Command+"'", "fix the version file, or ignore this if the hint is an alias such as lts/*", "%s", warning)
+		}
+		if e.Error != "" {
+			d.add(checkFail, "command '"+e.Command+"'", "fix select_by or the project's version files", "%s", e.Error)
+			continue
+		}
+		subject := "container '" + e.Container + "'"
+		if !reported[e.ContainerName] {
+			reported[e.ContainerName] = true
+			switch e.Status {
+			case "running":
+				d.add(checkPass, subject, "", "%s is running", e.ContainerName)
+			case "stopped":
+				d.add(checkFail, subject, "start it, e.g. with docker compose up -d", "%s is not running", e.ContainerName)
+			case "not found":
+				d.add(checkFail, subject, "check that containers."+e.Container+".name matches a name in docker ps -a", "no container named %s", e.ContainerName)
+			default:
+				d.add(checkWarn, subject, proxyHint(errorString(e.CheckError), "CONTAINERS=1"), "cannot inspect %s: %s", e.ContainerName, e.CheckError)
+			}
+		}
+		if e.Status != "running" || len(e.Exec) == 0 {
+			continue
+		}
+		subject = "command '" + e.Command + "'"
+		switch {
+		case e.ExecFound == nil:
+			d.add(checkFail, subject, proxyHint(errorString(e.CheckError), "EXEC=1 and POST=1"), "cannot run commands in %s: %s", e.ContainerName, e.CheckError)
+		case !*e.ExecFound:
+			d.add(checkFail, subject, "install it in the container's image or fix the command's exec", "%s not found in %s (workdir %s)", e.Exec[0], e.ContainerName, e.Workdir)
+		default:
+			d.add(checkPass, subject, "", "%s found in %s", e.Exec[0], e.ContainerName)
+		}
+	}
+
+	d.checkPaths(entries, !config.Trusted)
+}
+
+// checkPaths checks that each running container has the commands' workdirs
+// and the targets of their path mappings. A missing workdir fails every run
+// of the command; a missing mapping target only breaks translated paths.
+// Inferred mappings come from the cache only when cached is set.
+func (d *doctor) checkPaths(entries []routeEntry, cached bool) {
+	type pathCheck struct {
+		workdirs []string
+		targets  []string
+	}
+	checks := make(map[string]*pathCheck)
+	var order []string
+	for _, e := range entries {
+		if e.Status != "running" {
+			continue
+		}
+		c, ok := checks[e.ContainerName]
+		if !ok {
+			c = &pathCheck{}
+			checks[e.ContainerName] = c
+			order = append(order, e.ContainerName)
+		}
+		if !slices.Contains(c.workdirs, e.Workdir) {
+			c.workdirs = append(c.workdirs, e.Workdir)
+		}
+		paths := PathMap(e.Paths)
+		if e.AutoPaths {
+			paths = resolvePaths(PathMap(mergeStringMaps(e.Paths, map[string]string{autoPathsKey: autoPaths})), e.ContainerName, cached)
+		}
+		for _, source := range sortedKeys(paths) {
+			if !slices.Contains(c.targets, paths[source]) {
+				c.targets = append(c.targets, paths[source])
+			}
+		}
+	}
+
+	script := `for p in "$@"; do [ -e "$p" ] || echo "$p"; done; true`
+	for _, container := range order {
+		c := checks[container]
+		list := append(append([]string{}, c.workdirs...), c.targets...)
+		out, err := runDocker(append([]string{"exec", container, "sh", "-c", script, "sh"}, list...)...)
+		if err != nil {
+			d.add(checkWarn, "paths", proxyHint(err, "EXEC=1 and POST=1"), "cannot check paths in %s: %s", container, err)
+			continue
+		}
+		missing := strings.Split(strings.TrimSpace(string(out)), "\n")
+		problems := 0
+		for _, p := range c.workdirs {
+			if slices.Contains(missing, p) {
+				d.add(checkFail, "paths", "fix the command's workdir or path mappings", "workdir %s does not exist in %s", p, container)
+				problems++
+			}
+		}
+		for _, p := range c.targets {
+			if slices.Contains(missing, p) && !slices.Contains(c.workdirs, p) {
+				d.add(checkWarn, "paths", "fix the mapping or the container's volumes", "mapped path %s does not exist in %s", p, container)
+				problems++
+			}
+		}
+		if problems == 0 {
+			d.add(checkPass, "paths", "", "workdirs and mapped paths exist in %s", container)
+		}
+	}
+}
+
+// checkWrappers checks that each command's wrapper is on PATH ahead of any
+// native binary, and that the wrapper directory holds no stale symlinks.
+func (d *doctor) checkWrappers(config *Config, dir string) {
+	if dir == "" {
+		dispatcher, err := exec.LookPath("dispatcher")
+		if err != nil {
+			d.add(checkWarn, "wrappers", "add the wrapper directory (e.g. /scripts/wrappers) to PATH, or pass --wrappers", "dispatcher not found on PATH; commands only route through 'bridge <command>'")
+			return
+		}
+		dir = filepath.Dir(dispatcher)
+	}
+	dir, _ = filepath.Abs(dir)
+
+	names := make(map[string]bool)
+	for name := range config.Commands {
+		names[name] = true
+	}
+	for _, scope := range config.Scopes {
+		for name := range scope.Commands {
+			names[name] = true
+		}
+	}
+
+	missing := 0
+	for _, name := range sortedKeys(names) {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			d.add(checkFail, "wrapper '"+name+"'", "run bridge --init-wrappers "+dir, "not found on PATH")
+			missing++
+			continue
+		}
+		if abs, _ := filepath.Abs(path); filepath.Dir(abs) != dir {
+			d.add(checkWarn, "wrapper '"+name+"'", "put "+dir+" before "+filepath.Dir(abs)+" in PATH, or run bridge --init-wrappers "+dir, "%s resolves to %s, not the wrapper", name, path)
+			missing++
+		}
+	}
+	if missing == 0 {
+		d.add(checkPass, "wrappers", "", "%d wrapper(s) on PATH in %s", len(names), dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		d.add(checkWarn, "wrappers", "", "cannot read %s: %s", dir, err)
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		target, err := os.Readlink(path)
+		if err != nil {
+			continue // Not a symlink, e.g. the dispatcher itself
+		}
+		if _, err := os.Stat(path); err != nil {
+			d.add(checkWarn, "wrapper '"+entry.Name()+"'", "remove "+path, "broken symlink to %s", target)
+		} else if filepath.Base(target) == "dispatcher" && !names[entry.Name()] {
+			d.add(checkWarn, "wrapper '"+entry.Name()+"'", "remove "+path+" or add the command back to the config", "stale wrapper for a command not in the config")
+		}
+	}
+}
+
+// errorString wraps a recorded error message as an error.
+type errorString string
+
+func (e errorString) Error() string { return string(e) }
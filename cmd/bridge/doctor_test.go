@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoctorCheckDocker(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]string
+		ps        error
+		wantOK    bool
+		want      string
+	}{
+		{
+			name:      "reachable",
+			responses: map[string]string{"version --format {{.Server.Version}}": "27.3.1\n", "ps --quiet": ""},
+			wantOK:    true,
+			want:      "PASS  docker: Docker 27.3.1 reachable at",
+		},
+		{
+			name:   "unreachable",
+			wantOK: false,
+			want:   "hint: set DOCKER_HOST to the socket proxy",
+		},
+		{
+			name:      "proxy forbids listing",
+			responses: map[string]string{"version --format {{.Server.Version}}": "27.3.1\n"},
+			ps:        errors.New("Error response from daemon: 403 Forbidden"),
+			wantOK:    false,
+			want:      "set CONTAINERS=1 on it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", "")
+			fakeDocker(t, tt.responses)
+			if tt.ps != nil {
+				fake := runDocker
+				runDocker = func(args ...string) ([]byte, error) {
+					if args[0] == "ps" {
+						return nil, tt.ps
+					}
+					return fake(args...)
+				}
+			}
+
+			d := &doctor{}
+			if ok := d.checkDocker(); ok != tt.wantOK {
+				t.Errorf("checkDocker() = %v, want %v", ok, tt.wantOK)
+			}
+			var buf bytes.Buffer
+			d.write(&buf)
+			if !contains(buf.String(), tt.want) {
+				t.Errorf("output =\n%s\nwant it to contain %q", buf.String(), tt.want)
+			}
+			if d.failed() == tt.wantOK {
+				t.Errorf("failed() = %v", d.failed())
+			}
+		})
+	}
+}
+
+func TestDoctorCheckRoutes(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	probe := `for t in "$@"; do command -v "$t" >/dev/null 2>&1 && echo "$t"; done; true`
+	paths := `for p in "$@"; do [ -e "$p" ] || echo "$p"; done; true`
+	fakeDocker(t, map[string]string{
+		"inspect --type container shop-php-1":                                        `[{"Id": "a", "Name": "/shop-php-1", "State": {"Status": "running"}}]`,
+		"inspect --type container shop-node-1":                                       `[{"Id": "b", "Name": "/shop-node-1", "State": {"Status": "exited"}}]`,
+		"exec -w /var/www/html -u www-data shop-php-1 sh -c " + probe + " sh go php": "php\n",
+		"exec shop-php-1 sh -c " + paths + " sh /var/www/html /var/www/html/vendor":  "/var/www/html/vendor\n",
+	})
+
+	config := &Config{
+		Containers: map[string]ContainerConfig{
+			"php":  {Name: "shop-php-1", Workdir: "/var/www/html", User: "www-data", Paths: PathMap{dir + "/vendor": "/var/www/html/vendor"}},
+			"node": {Name: "shop-node-1"},
+		},
+		Commands: map[string]Command{
+			"php":  {Container: "php", Exec: []string{"php"}},
+			"go":   {Container: "php", Exec: []string{"go"}},
+			"npm":  {Container: "node", Exec: []string{"npm"}},
+			"rake": {Container: "ruby", Exec: []string{"rake"}},
+		},
+	}
+
+	d := &doctor{}
+	d.checkRoutes(config)
+	var buf bytes.Buffer
+	d.write(&buf)
+	got := buf.String()
+
+	want := []string{
+		"PASS  container 'php': shop-php-1 is running",
+		"FAIL  command 'go': go not found in shop-php-1 (workdir /var/www/html)",
+		"FAIL  container 'node': shop-node-1 is not running\n      hint: start it",
+		"PASS  command 'php': php found in shop-php-1",
+		"FAIL  container 'ruby': no container named ruby\n      hint: check that containers.ruby.name matches",
+		"WARN  paths: mapped path /var/www/html/vendor does not exist in shop-php-1",
+	}
+	for _, w := range want {
+		if !contains(got, w) {
+			t.Errorf("output =\n%s\nwant it to contain %q", got, w)
+		}
+	}
+	if contains(got, "command 'npm'") {
+		t.Errorf("commands in stopped containers should not be probed:\n%s", got)
+	}
+}
+
+func TestDoctorCheckWrappers(t *testing.T) {
+	wrappers := t.TempDir()
+	native := t.TempDir()
+	writeFiles(t, wrappers, map[string]string{"dispatcher": "#!/bin/sh\n"})
+	writeFiles(t, native, map[string]string{"php": "#!/bin/sh\n"})
+	for _, name := range []string{"dispatcher", "php"} {
+		if err := os.Chmod(filepath.Join(map[string]string{"dispatcher": wrappers, "php": native}[name], name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"npm", "php", "rake"} {
+		if err := os.Symlink("dispatcher", filepath.Join(wrappers, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("missing", filepath.Join(wrappers, "old")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", strings.Join([]string{native, wrappers}, string(os.PathListSeparator)))
+
+	config := &Config{Commands: map[string]Command{
+		"npm":      {Container: "node", Exec: []string{"npm"}},
+		"php":      {Container: "php", Exec: []string{"php"}},
+		"composer": {Container: "php", Exec: []string{"composer"}},
+	}}
+
+	d := &doctor{}
+	d.checkWrappers(config, "")
+	var buf bytes.Buffer
+	d.write(&buf)
+	got := buf.String()
+
+	want := []string{
+		"FAIL  wrapper 'composer': not found on PATH\n      hint: run bridge --init-wrappers " + wrappers,
+		"WARN  wrapper 'php': php resolves to " + filepath.Join(native, "php") + ", not the wrapper\n      hint: put " + wrappers + " before " + native + " in PATH",
+		"WARN  wrapper 'old': broken symlink to missing",
+		"WARN  wrapper 'rake': stale wrapper for a command not in the config",
+	}
+	for _, w := range want {
+		if !contains(got, w) {
+			t.Errorf("output =\n%s\nwant it to contain %q", got, w)
+		}
+	}
+	if contains(got, "wrapper 'npm'") {
+		t.Errorf("npm's wrapper is on PATH and should pass:\n%s", got)
+	}
+
+	// Without the dispatcher on PATH there is nothing to check
+	t.Setenv("PATH", native)
+	d = &doctor{}
+	d.checkWrappers(config, "")
+	if len(d.results) != 1 || d.results[0].Status != checkWarn || d.failed() {
+		t.Errorf("results = %+v, want one warning", d.results)
+	}
+}
+
+func TestDoctorReportsIgnoredVersionHints(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeFiles(t, dir, map[string]string{".nvmrc": "lts/*\n"})
+	fakeDocker(t, nil)
+
+	config := &Config{Commands: map[string]Command{
+		"node": {Container: "node", Exec: []string{"node"}, SelectBy: &SelectBy{Tool: "node", Candidates: []Candidate{{Container: "node20", Version: "20"}}}},
+	}}
+
+	d := &doctor{}
+	d.checkRoutes(config)
+	var buf bytes.Buffer
+	d.write(&buf)
+	if want := "WARN  command 'node': ignoring node version 'lts/*' in " + filepath.Join(dir, ".nvmrc"); !contains(buf.String(), want) {
+		t.Errorf("output =\n%s\nwant it to contain %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// tracer reports the decisions routeCommand makes. A nil tracer reports
+// nothing.
+type tracer struct {
+	w      io.Writer
+	prefix string
+}
+
+// debugTracer returns a tracer writing to stderr when BRIDGE_DEBUG is set,
+// and nil otherwise.
+func debugTracer() *tracer {
+	switch strings.ToLower(os.Getenv("BRIDGE_DEBUG")) {
+	case "", "0", "false", "no":
+		return nil
+	}
+	return &tracer{w: os.Stderr, prefix: "bridge: "}
+}
+
+// enabled reports whether the tracer writes anything.
+func (t *tracer) enabled() bool {
+	return t != nil && t.w != nil
+}
+
+// printf writes one trace line.
+func (t *tracer) printf(format string, args ...any) {
+	if !t.enabled() {
+		return
+	}
+	fmt.Fprintf(t.w, t.prefix+format+"\n", args...)
+}
+
+// explainCommand implements `bridge explain`: it routes the command line
+// like a real run and prints each decision, without executing anything.
+func explainCommand(config *Config, args []string) int {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: bridge explain <command> [args...]")
+		return 1
+	}
+
+	inv, err := routeCommand(config, args, &tracer{w: os.Stdout})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitCode(err)
+	}
+	if inv.Native != "" {
+		fmt.Printf("run: %s\n", shellJoin(append([]string{inv.Native}, inv.Args...)))
+	}
+	return 0
+}
+
+// filledDefaults names the fields ApplyContainerDefaults filled in.
+func filledDefaults(before, after Command) []string {
+	var filled []string
+	if before.Workdir != after.Workdir {
+		filled = append(filled, "workdir "+after.Workdir)
+	}
+	if len(before.Paths) != len(after.Paths) {
+		filled = append(filled, "paths")
+	}
+	if before.User != after.User {
+		filled = append(filled, "user "+after.User)
+	}
+	var env []string
+	for _, k := range sortedKeys(after.Env) {
+		if _, ok := before.Env[k]; !ok {
+			env = append(env, k)
+		}
+	}
+	if len(env) > 0 {
+		filled = append(filled, "env "+strings.Join(env, ", "))
+	}
+	return filled
+}
+
+// describePaths formats path mappings for a trace line.
+func describePaths(paths PathMap, auto bool) string {
+	var mappings []string
+	for _, source := range sortedKeys(paths) {
+		mappings = append(mappings, source+" -> "+paths[source])
+	}
+	desc := "none"
+	if len(mappings) > 0 {
+		desc = strings.Join(mappings, ", ")
+	}
+	if auto {
+		desc += " (paths: auto, inferred from mounts)"
+	}
+	return desc
+}
+
+// shellJoin quotes args for display as a POSIX shell command line.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes a word for a POSIX shell if it needs quoting.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouteCommandTrace(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeFiles(t, dir, map[string]string{".nvmrc": "20\n"})
+
+	config := &Config{
+		Sources: []string{"/project/.sidecar/bridge.yaml"},
+		Containers: map[string]ContainerConfig{
+			"node20": {Name: "shop-node20-1", User: "node", Env: map[string]string{"CI": "1"}},
+		},
+		Commands: map[string]Command{
+			"npm": {
+				Container: "node18",
+				Exec:      []string{"npm"},
+				Paths:     PathMap{dir: "/app", dir + "/vendor": "/opt/vendor"},
+				SelectBy: &SelectBy{Tool: "node", Candidates: []Candidate{
+					{Container: "node18", Version: "18"},
+					{Container: "node20", Version: "20"},
+				}},
+				Routes: []Route{{Match: RouteMatch{Prefix: []string{"test"}}, Env: map[string]string{"NODE_ENV": "test"}}},
+			},
+		},
+		Origins: map[string]string{"commands.npm": "/project/.sidecar/bridge.yaml:7"},
+	}
+
+	var buf bytes.Buffer
+	inv, err := routeCommand(config, []string{"npm", "test", dir + "/vendor/a.js", "--watch"}, &tracer{w: &buf})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"config: /project/.sidecar/bridge.yaml",
+		"command: 'npm' (/project/.sidecar/bridge.yaml:7)",
+		"toolchain: " + filepath.Join(dir, ".nvmrc") + " asks for node 20; selected container 'node20'",
+		"route: arguments match route 0",
+		"defaults: user node, env CI from container 'node20'",
+		"container: 'node20' is shop-node20-1",
+		"paths: " + dir + " -> /app, " + dir + "/vendor -> /opt/vendor",
+		"arg 1: test (no mapping)",
+		"arg 2: " + dir + "/vendor/a.js -> /opt/vendor/a.js (mapping " + dir + "/vendor)",
+		"arg 3: --watch (no mapping)",
+		"workdir: /app (current directory " + dir + " translated by mapping " + dir + ")",
+		"run: docker exec -i -w /app -u node -e CI=1 -e NODE_ENV=test shop-node20-1 npm test /opt/vendor/a.js --watch",
+	}
+	if got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("trace =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if inv.Container != "shop-node20-1" || inv.Workdir != "/app" {
+		t.Errorf("invocation = %+v", inv)
+	}
+}
+
+func TestRouteCommandErrors(t *testing.T) {
+	config := &Config{Commands: map[string]Command{
+		"bad": {Container: "app", Exec: []string{"x"}, Template: "{{1}}"},
+	}}
+
+	tests := []struct {
+		args     []string
+		wantErr  string
+		wantCode int
+	}{
+		{args: []string{"bridge-no-such-command"}, wantErr: "command 'bridge-no-such-command' not found in config and not available natively", wantCode: 127},
+		{args: []string{"bad"}, wantErr: "command 'bad':", wantCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args[0], func(t *testing.T) {
+			_, err := routeCommand(config, tt.args, nil)
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if code := exitCode(err); code != tt.wantCode {
+				t.Errorf("exitCode = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"/var/www/html", "/var/www/html"},
+		{"KEY=value", "KEY=value"},
+		{"", "''"},
+		{"two words", "'two words'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+	}
+
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mergeCommand returns child layered over parent. Non-empty child fields
+// replace the parent's; env and paths are merged per key with the child
+// winning.
+func mergeCommand(parent, child Command) Command {
+	merged := parent
+	merged.Extends = ""
+
+	if child.Container != "" {
+		merged.Container = child.Container
+	}
+	if child.Exec != nil {
+		merged.Exec = child.Exec
+	}
+	if child.Workdir != "" {
+		merged.Workdir = child.Workdir
+	}
+	if child.User != "" {
+		merged.User = child.User
+	}
+	if child.Template != "" {
+		merged.Template = child.Template
+	}
+	if child.Routes != nil {
+		merged.Routes = child.Routes
+	}
+	if child.SelectBy != nil {
+		merged.SelectBy = child.SelectBy
+	}
+	if child.ArgsPrefix != nil {
+		merged.ArgsPrefix = child.ArgsPrefix
+	}
+	if child.ArgsSuffix != nil {
+		merged.ArgsSuffix = child.ArgsSuffix
+	}
+	merged.Paths = mergeStringMaps(parent.Paths, child.Paths)
+	merged.Env = mergeStringMaps(parent.Env, child.Env)
+	return merged
+}
+
+// mergeStringMaps returns the entries of b layered over a. A new map is
+// allocated only when both have entries.
+func mergeStringMaps(a, b map[string]string) map[string]string {
+	if len(b) == 0 {
+		return a
+	}
+	if len(a) == 0 {
+		return b
+	}
+	merged := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		merged[k] = v
+	}
+	for k, v := range b {
+		merged[k] = v
+	}
+	return merged
+}
+
+// resolveExtends returns the named command with its extends chain applied.
+// scope is the scope's command table, or nil for a top-level command. A
+// scope command may extend commands in its own scope or at the top level;
+// extending its own name refers to the top-level command of that name.
+// Cycles and unknown parents are errors.
+func (c *Config) resolveExtends(name string, scope map[string]Command) (Command, error) {
+	inScope := scope != nil
+	cmd := c.Commands[name]
+	if inScope {
+		cmd = scope[name]
+	}
+
+	chain := []string{name}
+	visited := map[string]bool{tableKey(inScope, name): true}
+	var ancestors []Command
+
+	for cmd.Extends != "" {
+		parentName := cmd.Extends
+		parent, ok := Command{}, false
+		if inScope && parentName != name {
+			parent, ok = scope[parentName]
+		}
+		if !ok {
+			parent, ok = c.Commands[parentName]
+			inScope = false
+		}
+		if !ok {
+			return Command{}, fmt.Errorf("extends unknown command '%s'", parentName)
+		}
+
+		chain = append(chain, parentName)
+		key := tableKey(inScope, parentName)
+		if visited[key] {
+			return Command{}, fmt.Errorf("extends cycle: %s", strings.Join(chain, " -> "))
+		}
+		visited[key] = true
+
+		ancestors = append(ancestors, cmd)
+		cmd = parent
+		name = parentName
+	}
+
+	// Apply overrides from the most distant ancestor down to the command itself
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		cmd = mergeCommand(cmd, ancestors[i])
+	}
+	return cmd, nil
+}
+
+// tableKey identifies a command within the top level or the scope being resolved.
+func tableKey(inScope bool, name string) string {
+	if inScope {
+		return "scope:" + name
+	}
+	return name
+}
+
+// expandExtends replaces every command (top-level and scoped) with its
+// resolved form. It must run after Validate has checked for cycles.
+func (c *Config) expandExtends() error {
+	resolved := make(map[string]Command, len(c.Commands))
+	for name := range c.Commands {
+		cmd, err := c.resolveExtends(name, nil)
+		if err != nil {
+			return fmt.Errorf("command '%s': %w", name, err)
+		}
+		resolved[name] = cmd
+	}
+
+	scopes := make(map[string]Scope, len(c.Scopes))
+	for prefix, scope := range c.Scopes {
+		commands := make(map[string]Command, len(scope.Commands))
+		for name := range scope.Commands {
+			cmd, err := c.resolveExtends(name, scope.Commands)
+			if err != nil {
+				return fmt.Errorf("scope '%s': command '%s': %w", prefix, name, err)
+			}
+			commands[name] = cmd
+		}
+		scopes[prefix] = Scope{Commands: commands}
+	}
+
+	c.Commands = resolved
+	if c.Scopes != nil {
+		c.Scopes = scopes
+	}
+	return nil
+}
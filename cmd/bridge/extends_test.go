@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveExtends(t *testing.T) {
+	config := &Config{
+		Version: "1",
+		Commands: map[string]Command{
+			"php": {
+				Container: "php",
+				Exec:      []string{"php"},
+				Workdir:   "/var/www/html",
+				Paths:     map[string]string{"/workspace": "/var/www/html"},
+				Env:       map[string]string{"APP_ENV": "local"},
+			},
+			"artisan": {Extends: "php", Exec: []string{"php", "artisan"}},
+			"test:php": {
+				Extends: "artisan",
+				Exec:    []string{"php", "artisan", "test"},
+				Env:     map[string]string{"APP_ENV": "testing"},
+			},
+			"go": {Container: "golang", Exec: []string{"go"}},
+		},
+		Scopes: map[string]Scope{
+			"/workspace/services/billing": {
+				Commands: map[string]Command{
+					"go":    {Extends: "go", Container: "billing-go"},
+					"gofmt": {Extends: "go", Exec: []string{"gofmt"}},
+				},
+			},
+		},
+	}
+
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cmd, err := config.resolveExtends("test:php", nil)
+	if err != nil {
+		t.Fatalf("resolveExtends: %v", err)
+	}
+	if cmd.Container != "php" || strings.Join(cmd.Exec, " ") != "php artisan test" || cmd.Workdir != "/var/www/html" {
+		t.Errorf("test:php resolved to %+v", cmd)
+	}
+	if cmd.Paths["/workspace"] != "/var/www/html" || cmd.Env["APP_ENV"] != "testing" {
+		t.Errorf("test:php maps resolved to %+v", cmd)
+	}
+	if cmd.Extends != "" {
+		t.Errorf("resolved command should not keep extends: %q", cmd.Extends)
+	}
+
+	scope := config.Scopes["/workspace/services/billing"].Commands
+	cmd, err = config.resolveExtends("go", scope)
+	if err != nil {
+		t.Fatalf("resolveExtends scoped go: %v", err)
+	}
+	if cmd.Container != "billing-go" || strings.Join(cmd.Exec, " ") != "go" {
+		t.Errorf("scoped go resolved to %+v", cmd)
+	}
+
+	// gofmt extends the scope's own go, which extends the top-level go
+	cmd, err = config.resolveExtends("gofmt", scope)
+	if err != nil {
+		t.Fatalf("resolveExtends scoped gofmt: %v", err)
+	}
+	if cmd.Container != "billing-go" || strings.Join(cmd.Exec, " ") != "gofmt" {
+		t.Errorf("scoped gofmt resolved to %+v", cmd)
+	}
+
+	if err := config.expandExtends(); err != nil {
+		t.Fatalf("expandExtends: %v", err)
+	}
+	if config.Commands["artisan"].Container != "php" {
+		t.Errorf("expandExtends did not resolve artisan: %+v", config.Commands["artisan"])
+	}
+}
+
+func TestValidateExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		commands      map[string]Command
+		errorContains string
+	}{
+		{
+			name: "self cycle",
+			commands: map[string]Command{
+				"php": {Extends: "php", Container: "php", Exec: []string{"php"}},
+			},
+			errorContains: "extends cycle: php -> php",
+		},
+		{
+			name: "indirect cycle",
+			commands: map[string]Command{
+				"a": {Extends: "b"},
+				"b": {Extends: "c"},
+				"c": {Extends: "a"},
+			},
+			errorContains: "extends cycle:",
+		},
+		{
+			name: "unknown parent",
+			commands: map[string]Command{
+				"artisan": {Extends: "php", Exec: []string{"php", "artisan"}},
+			},
+			errorContains: "command 'artisan': extends unknown command 'php'",
+		},
+		{
+			name: "inherited command still needs container",
+			commands: map[string]Command{
+				"base":    {Exec: []string{"php"}},
+				"artisan": {Extends: "base"},
+			},
+			errorContains: "missing required field 'container'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Version: "1", Commands: tt.commands}
+			err := config.Validate()
+			if err == nil || !contains(err.Error(), tt.errorContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errorContains, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fragmentDirName is the directory next to a bridge.yaml whose *.yaml files
+// are loaded automatically, in lexical order, as part of the same layer.
+const fragmentDirName = "bridge.d"
+
+// fragment is a parsed file contributing to a layer.
+type fragment struct {
+	path string
+	root *yaml.Node
+}
+
+// fragmentLoader collects a config file and, recursively, the files it includes.
+// Each file is upgraded to the current config version as it is read.
+type fragmentLoader struct {
+	files     configReader
+	loaded    map[string]bool
+	stack     []string
+	fragments []fragment
+}
+
+// loadLayerFile reads the config file at path together with its include:
+// directives and, for files named bridge.yaml, the sibling bridge.d/*.yaml
+// fragments. The fragments are combined into a single layer; an entry
+// defined by more than one fragment is an error.
+func loadLayerFile(path string, files configReader) (configLayer, error) {
+	loader := &fragmentLoader{files: files, loaded: make(map[string]bool)}
+	version, err := loader.load(path, "")
+	if err != nil {
+		return configLayer{}, err
+	}
+
+	if filepath.Base(path) == "bridge.yaml" {
+		pattern := filepath.Join(filepath.Dir(path), fragmentDirName, "*.yaml")
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return configLayer{}, fmt.Errorf("invalid fragment pattern %s: %w", pattern, err)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			if _, err := loader.load(m, version); err != nil {
+				return configLayer{}, err
+			}
+		}
+	}
+
+	return combineFragments(path, loader.fragments)
+}
+
+// load reads a file and the files it includes, depth first, and returns
+// the file's config version. A file without a version key inherits version
+// from the file that includes it. Files already loaded through another
+// include are skipped.
+func (l *fragmentLoader) load(path, version string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	for i, p := range l.stack {
+		if p == abs {
+			cycle := append(append([]string{}, l.stack[i:]...), abs)
+			return "", fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	if l.loaded[abs] {
+		return version, nil
+	}
+	l.loaded[abs] = true
+
+	root, err := readConfigFile(path, l.files)
+	if err != nil {
+		return "", err
+	}
+	version = upgradeFile(root, version)
+	includes, err := takeIncludes(root, path)
+	if err != nil {
+		return "", err
+	}
+	l.fragments = append(l.fragments, fragment{path: path, root: root})
+
+	l.stack = append(l.stack, abs)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	for _, inc := range includes {
+		pattern := inc.Value
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", fmt.Errorf("%s:%d: invalid include pattern '%s': %w", path, inc.Line, inc.Value, err)
+		}
+		if len(matches) == 0 && !hasGlobMeta(inc.Value) {
+			return "", fmt.Errorf("%s:%d: included file not found: %s", path, inc.Line, pattern)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			if _, err := l.load(m, version); err != nil {
+				return "", err
+			}
+		}
+	}
+	return version, nil
+}
+
+// takeIncludes removes the include: key from root and returns its entries.
+// include may be a single string or a list of strings.
+func takeIncludes(root *yaml.Node, path string) ([]*yaml.Node, error) {
+	idx := mappingIndex(root, "include")
+	if idx < 0 {
+		return nil, nil
+	}
+	value := root.Content[idx+1]
+	root.Content = append(root.Content[:idx], root.Content[idx+2:]...)
+
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if isNull(value) {
+			return nil, nil
+		}
+		return []*yaml.Node{value}, nil
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("%s:%d: include entries must be file paths", path, item.Line)
+			}
+		}
+		return value.Content, nil
+	}
+	return nil, fmt.Errorf("%s:%d: include must be a file path or a list of file paths", path, value.Line)
+}
+
+// recordNodeFiles maps every node in a tree to the file it was read from.
+func recordNodeFiles(node *yaml.Node, file string, nodeFiles map[*yaml.Node]string) {
+	nodeFiles[node] = file
+	for _, child := range node.Content {
+		recordNodeFiles(child, file, nodeFiles)
+	}
+}
+
+// hasGlobMeta reports whether a path contains glob metacharacters.
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// combineFragments combines the fragments of a layer into a single mapping.
+// Section entries (containers, commands, scopes, profiles) are collected
+// from every fragment; defining the same entry twice is an error naming
+// both files. Other top-level keys may only be repeated with equal values.
+func combineFragments(path string, fragments []fragment) (configLayer, error) {
+	layer := configLayer{
+		Path:      path,
+		Root:      &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"},
+		nodeFiles: make(map[*yaml.Node]string),
+	}
+
+	for _, f := range fragments {
+		layer.Files = append(layer.Files, f.path)
+		recordNodeFiles(f.root, f.path, layer.nodeFiles)
+		for i := 0; i+1 < len(f.root.Content); i += 2 {
+			key, value := f.root.Content[i], f.root.Content[i+1]
+			idx := mappingIndex(layer.Root, key.Value)
+
+			if !mergedSections[key.Value] || value.Kind != yaml.MappingNode {
+				if idx >= 0 {
+					existing := layer.Root.Content[idx+1]
+					if existing.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode && existing.Value == value.Value {
+						continue
+					}
+					return configLayer{}, fmt.Errorf("'%s' is set in both %s and %s:%d",
+						key.Value, layer.origin(layer.Root.Content[idx]), f.path, key.Line)
+				}
+				layer.Root.Content = append(layer.Root.Content, key, value)
+				continue
+			}
+
+			var section *yaml.Node
+			if idx >= 0 && layer.Root.Content[idx+1].Kind == yaml.MappingNode {
+				section = layer.Root.Content[idx+1]
+			} else {
+				section = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: value.Line, Column: value.Column}
+				layer.Root.Content = append(layer.Root.Content, key, section)
+				layer.nodeFiles[section] = f.path
+			}
+
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				entryKey := value.Content[j]
+				if e := mappingIndex(section, entryKey.Value); e >= 0 {
+					return configLayer{}, fmt.Errorf("%s '%s' is defined in both %s and %s:%d",
+						strings.TrimSuffix(key.Value, "s"), entryKey.Value,
+						layer.origin(section.Content[e]), f.path, entryKey.Line)
+				}
+				section.Content = append(section.Content, entryKey, value.Content[j+1])
+			}
+		}
+	}
+
+	return layer, nil
+}
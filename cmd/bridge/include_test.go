@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles writes files relative to dir, creating parent directories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+}
+
+func TestLoadConfigIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"bridge.yaml": `version: "1"
+include:
+  - stacks/*.yaml
+  - shared/containers.yaml
+commands:
+  go:
+    container: golang
+    exec: go
+`,
+		"stacks/node.yaml": `commands:
+  npm:
+    container: node
+    exec: npm
+`,
+		"stacks/php.yaml": `include: ../shared/containers.yaml
+commands:
+  php:
+    container: php
+    exec: php
+`,
+		"shared/containers.yaml": `containers:
+  php: shop-php-1
+  node: shop-node-1
+`,
+		"bridge.d/10-db.yaml": `commands:
+  psql:
+    container: db
+    exec: psql
+`,
+		"bridge.d/20-tools.yaml": `version: "1"
+commands:
+  gofmt:
+    container: golang
+    exec: gofmt
+`,
+		"bridge.d/notes.txt": "not loaded",
+	})
+
+	config, err := LoadConfig(filepath.Join(dir, "bridge.yaml"), "")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	for _, name := range []string{"go", "npm", "php", "psql", "gofmt"} {
+		if _, ok := config.Commands[name]; !ok {
+			t.Errorf("command %q missing", name)
+		}
+	}
+	if config.ResolveContainer("php") != "shop-php-1" {
+		t.Errorf("php resolved to %q", config.ResolveContainer("php"))
+	}
+
+	expected := []string{
+		filepath.Join(dir, "bridge.yaml"),
+		filepath.Join(dir, "stacks/node.yaml"),
+		filepath.Join(dir, "stacks/php.yaml"),
+		filepath.Join(dir, "stacks/../shared/containers.yaml"),
+		filepath.Join(dir, "bridge.d/10-db.yaml"),
+		filepath.Join(dir, "bridge.d/20-tools.yaml"),
+	}
+	if len(config.Sources) != len(expected) {
+		t.Fatalf("Sources = %v, want %v", config.Sources, expected)
+	}
+	for i := range expected {
+		if config.Sources[i] != expected[i] {
+			t.Errorf("Sources[%d] = %q, want %q", i, config.Sources[i], expected[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := showConfig(&buf, filepath.Join(dir, "bridge.yaml"), true); err != nil {
+		t.Fatalf("showConfig: %v", err)
+	}
+	if want := "npm: # from " + filepath.Join(dir, "stacks/node.yaml") + ":2"; !contains(buf.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestLoadConfigIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         map[string]string
+		errorContains []string
+	}{
+		{
+			name: "conflicting command names both files",
+			files: map[string]string{
+				"bridge.yaml": "version: \"1\"\ninclude: [a.yaml, b.yaml]\n",
+				"a.yaml":      "commands:\n  npm:\n    container: node\n    exec: npm\n",
+				"b.yaml":      "commands:\n  yarn:\n    container: node\n    exec: yarn\n  npm:\n    container: node20\n    exec: npm\n",
+			},
+			errorContains: []string{"command 'npm' is defined in both", "a.yaml:2", "b.yaml:5"},
+		},
+		{
+			name: "conflict with bridge.d fragment",
+			files: map[string]string{
+				"bridge.yaml":          "version: \"1\"\ncommands:\n  go:\n    container: golang\n    exec: go\n",
+				"bridge.d/golang.yaml": "commands:\n  go:\n    container: go124\n    exec: go\n",
+			},
+			errorContains: []string{"command 'go' is defined in both", "bridge.yaml:3", "golang.yaml:2"},
+		},
+		{
+			name: "conflicting scalar",
+			files: map[string]string{
+				"bridge.yaml": "version: \"1\"\ndefault_container: app\ninclude: a.yaml\ncommands:\n  go:\n    container: golang\n    exec: go\n",
+				"a.yaml":      "default_container: php\n",
+			},
+			errorContains: []string{"'default_container' is set in both", "bridge.yaml:2", "a.yaml:1"},
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"bridge.yaml": "version: \"1\"\ninclude: a.yaml\n",
+				"a.yaml":      "include: b.yaml\n",
+				"b.yaml":      "include: a.yaml\n",
+			},
+			errorContains: []string{"include cycle", "a.yaml -> ", "b.yaml -> "},
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"bridge.yaml": "version: \"1\"\ninclude:\n  - missing.yaml\n",
+			},
+			errorContains: []string{"bridge.yaml:3: included file not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			_, err := LoadConfig(filepath.Join(dir, "bridge.yaml"), "")
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			for _, want := range tt.errorContains {
+				if !contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestIncludeGlobWithoutMatches(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"bridge.yaml": "version: \"1\"\ninclude: stacks/*.yaml\ncommands:\n  go:\n    container: golang\n    exec: go\n",
+	})
+	if _, err := LoadConfig(filepath.Join(dir, "bridge.yaml"), ""); err != nil {
+		t.Errorf("glob without matches should not fail: %v", err)
+	}
+}
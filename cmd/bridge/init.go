@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// toolCatalogue lists the tools `bridge config init` looks for, in the
+// order their commands are written.
+var toolCatalogue = []string{
+	"php", "composer",
+	"node", "npm", "npx",
+	"go",
+	"python", "pip",
+	"ruby", "bundle",
+	"cargo",
+	"mysql", "psql",
+}
+
+// toolFamilies lists, per tool, the words that mark a container as the
+// natural home for it when found in its image or service name. A Node.js
+// image often ships Python too, but python belongs in the python container.
+var toolFamilies = map[string][]string{
+	"php":      {"php", "laravel", "symfony"},
+	"composer": {"php", "composer", "laravel", "symfony"},
+	"node":     {"node", "nodejs"},
+	"npm":      {"node", "nodejs"},
+	"npx":      {"node", "nodejs"},
+	"go":       {"go", "golang"},
+	"python":   {"python"},
+	"pip":      {"python"},
+	"ruby":     {"ruby", "rails"},
+	"bundle":   {"ruby", "rails"},
+	"cargo":    {"rust", "cargo"},
+	"mysql":    {"mysql", "mariadb"},
+	"psql":     {"postgres", "postgresql", "postgis"},
+}
+
+// initContainer is a running container considered by `bridge config init`.
+type initContainer struct {
+	Logical string // Name used in the generated config
+	Info    containerInfo
+	Tools   []string          // Catalogue tools found on the container's PATH
+	Paths   map[string]string // Path mappings from the bridge's view to the container's
+	User    string            // User to run commands as, if set explicitly
+	Env     map[string]string // Environment for every command in the container
+}
+
+// initCommand routes one tool to a container.
+type initCommand struct {
+	Tool       string
+	Container  string   // Logical container name
+	Candidates []string // Every container providing the tool, preferred first
+}
+
+// initPlan is the config `bridge config init` or `import-compose` is about
+// to write.
+type initPlan struct {
+	Project    string
+	Main       string // Logical container kept even if no command uses it
+	Containers []initContainer
+	Presets    []initPreset
+	Commands   []initCommand
+}
+
+// initPreset uses a built-in preset with the given container.
+type initPreset struct {
+	Name      string
+	Container string // Logical container name
+}
+
+// configInitCommand implements `bridge config init`.
+func configInitCommand(args []string, configPath string) int {
+	fs := flag.NewFlagSet("config init", flag.ContinueOnError)
+	project := fs.String("project", "", "Compose project to probe (default: $COMPOSE_PROJECT_NAME or the bridge's own project)")
+	interactive := fs.Bool("interactive", false, "Choose which tools to route and where")
+	fs.BoolVar(interactive, "i", false, "Choose which tools to route and where (shorthand)")
+	force := fs.Bool("force", false, "Overwrite an existing config file")
+	dryRun := fs.Bool("dry-run", false, "Print the config instead of writing it")
+	presetList := fs.String("preset", "", "Comma-separated presets to use ("+strings.Join(presetNames(), ", ")+")")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	path, err := editTarget(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if _, err := os.Stat(path); err == nil && !*force && !*dryRun {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use --force to overwrite it)\n", path)
+		return 1
+	}
+
+	plan, err := probeInitPlan(*project)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if *interactive {
+		chooseCommands(bufio.NewReader(os.Stdin), os.Stderr, &plan)
+	}
+	if *presetList != "" {
+		if err := plan.usePresets(strings.Split(*presetList, ",")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return 1
+		}
+	}
+	plan.prune()
+	if len(plan.Commands) == 0 && len(plan.Presets) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no commands to write")
+		return 1
+	}
+
+	data, err := renderInitConfig(plan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if *dryRun {
+		os.Stdout.Write(data)
+		return 0
+	}
+
+	if err := writeNewConfig(path, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s with %d command(s) and %d preset(s) in %d container(s)\n", path, len(plan.Commands), len(plan.Presets), len(plan.Containers))
+	return 0
+}
+
+// writeNewConfig writes a generated config file, creating its directory.
+func writeNewConfig(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// probeInitPlan lists the running containers of the compose project and
+// probes each for the catalogue tools. Without a project, every running
+// container except the bridge's own is probed.
+func probeInitPlan(project string) (initPlan, error) {
+	self, selfErr := selfContainer()
+	if project == "" {
+		project = os.Getenv("COMPOSE_PROJECT_NAME")
+	}
+	if project == "" && selfErr == nil {
+		project = self.Project
+	}
+	if project == "" {
+		fmt.Fprintln(os.Stderr, "No compose project found; probing every running container")
+	}
+
+	infos, err := listContainers(project)
+	if err != nil {
+		return initPlan{}, err
+	}
+
+	var containers []initContainer
+	for _, info := range infos {
+		if selfErr == nil && info.ID == self.ID {
+			continue
+		}
+		tools, err := probeTools(info.Name, toolCatalogue)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %s\n", info.Name, err)
+			continue
+		}
+		c := initContainer{Info: info, Tools: tools}
+		if selfErr == nil {
+			if paths := mountPaths(self.Mounts, info.Mounts); len(paths) > 0 {
+				c.Paths = paths
+			}
+		}
+		containers = append(containers, c)
+	}
+	if len(containers) == 0 {
+		if project != "" {
+			return initPlan{}, fmt.Errorf("no running containers found in compose project '%s'", project)
+		}
+		return initPlan{}, fmt.Errorf("no running containers found")
+	}
+	return buildInitPlan(project, containers), nil
+}
+
+// buildInitPlan names the containers and routes each catalogue tool to the
+// container that suits it best (see toolAffinity), falling back to the
+// first container (by name) that has it.
+func buildInitPlan(project string, containers []initContainer) initPlan {
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].Info.Name < containers[j].Info.Name
+	})
+
+	used := make(map[string]bool)
+	for i := range containers {
+		c := &containers[i]
+		c.Logical = c.Info.Service
+		if c.Logical == "" || used[c.Logical] {
+			c.Logical = c.Info.Name
+		}
+		used[c.Logical] = true
+	}
+
+	plan := initPlan{Project: project, Containers: containers}
+	for _, tool := range toolCatalogue {
+		var ranked []initContainer
+		for _, c := range containers {
+			if slices.Contains(c.Tools, tool) {
+				ranked = append(ranked, c)
+			}
+		}
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return toolAffinity(tool, ranked[i]) > toolAffinity(tool, ranked[j])
+		})
+		candidates := make([]string, 0, len(ranked))
+		for _, c := range ranked {
+			candidates = append(candidates, c.Logical)
+		}
+		if len(candidates) > 0 {
+			plan.Commands = append(plan.Commands, initCommand{Tool: tool, Container: candidates[0], Candidates: candidates})
+		}
+	}
+	return plan
+}
+
+// toolAffinity ranks how well a container suits a tool: a container whose
+// image or service name fits the tool comes first, and among those one that
+// shares the project with the bridge (has path mappings) wins.
+func toolAffinity(tool string, c initContainer) int {
+	score := 0
+	if matchesFamily(tool, c) {
+		score += 2
+	}
+	if len(c.Paths) > 0 {
+		score++
+	}
+	return score
+}
+
+// matchesFamily reports whether a container's image or service name
+// contains one of the tool's family words.
+func matchesFamily(tool string, c initContainer) bool {
+	image := c.Info.Image
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		image = image[i+1:]
+	}
+	words := strings.FieldsFunc(strings.ToLower(image+" "+c.Info.Service), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	})
+	for _, word := range words {
+		if slices.Contains(toolFamilies[tool], word) {
+			return true
+		}
+	}
+	return false
+}
+
+// usePresets replaces the probed commands a preset covers with the preset,
+// run in the container chosen for the preset's main tool.
+func (p *initPlan) usePresets(names []string) error {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		ps, ok := presets[name]
+		if !ok {
+			return fmt.Errorf("unknown preset '%s' (expected one of: %s)", name, strings.Join(presetNames(), ", "))
+		}
+
+		container := ""
+		for _, cmd := range p.Commands {
+			if cmd.Tool == ps.tool {
+				container = cmd.Container
+			}
+		}
+		if container == "" {
+			return fmt.Errorf("preset '%s': no container provides %s", name, ps.tool)
+		}
+		p.Presets = append(p.Presets, initPreset{Name: name, Container: container})
+
+		covered := presetCommandNames(name)
+		var commands []initCommand
+		for _, cmd := range p.Commands {
+			if !slices.Contains(covered, cmd.Tool) {
+				commands = append(commands, cmd)
+			}
+		}
+		p.Commands = commands
+	}
+	return nil
+}
+
+// prune drops skipped commands and containers no command or preset uses.
+// The main container is kept.
+func (p *initPlan) prune() {
+	var commands []initCommand
+	used := map[string]bool{p.Main: true}
+	for _, ps := range p.Presets {
+		used[ps.Container] = true
+	}
+	for _, cmd := range p.Commands {
+		if cmd.Container != "" {
+			commands = append(commands, cmd)
+			used[cmd.Container] = true
+		}
+	}
+	var containers []initContainer
+	for _, c := range p.Containers {
+		if used[c.Logical] {
+			containers = append(containers, c)
+		}
+	}
+	p.Commands, p.Containers = commands, containers
+}
+
+// chooseCommands asks, for each tool, which container should run it. An
+// empty answer keeps the suggested container and 0 skips the tool.
+func chooseCommands(in *bufio.Reader, out io.Writer, plan *initPlan) {
+	for i := range plan.Commands {
+		cmd := &plan.Commands[i]
+		options := make([]string, 0, len(cmd.Candidates))
+		for n, name := range cmd.Candidates {
+			options = append(options, fmt.Sprintf("%d) %s", n+1, name))
+		}
+		for {
+			fmt.Fprintf(out, "Route %s to: %s, 0) skip [1]: ", cmd.Tool, strings.Join(options, ", "))
+			answer, err := in.ReadString('\n')
+			answer = strings.TrimSpace(answer)
+			if answer == "" {
+				break
+			}
+			n, convErr := strconv.Atoi(answer)
+			if convErr == nil && n >= 0 && n <= len(cmd.Candidates) {
+				cmd.Container = ""
+				if n > 0 {
+					cmd.Container = cmd.Candidates[n-1]
+				}
+				break
+			}
+			if err != nil {
+				break
+			}
+			fmt.Fprintf(out, "Please answer a number from 0 to %d.\n", len(cmd.Candidates))
+		}
+	}
+}
+
+// renderInitConfig writes the plan as a commented version 2 bridge.yaml and
+// checks that it is valid.
+func renderInitConfig(plan initPlan) ([]byte, error) {
+	header := "# Generated by `bridge config init` from the running containers"
+	if plan.Project != "" {
+		header += "\n# of compose project '" + plan.Project + "'"
+	}
+	return renderPlan(plan, header+".", "# Tools found on each container's PATH with `command -v`.")
+}
+
+// renderPlan writes a plan as a version 2 bridge.yaml under the given
+// header comment, with commandsComment above the commands section.
+func renderPlan(plan initPlan, header, commandsComment string) ([]byte, error) {
+	header += "\n# See examples/claude-bridge.yaml for everything bridge.yaml can do."
+
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	version := scalarNode("version")
+	version.HeadComment = header
+	root.Content = append(root.Content, version, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: currentVersion, Style: yaml.DoubleQuotedStyle})
+
+	containers := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, c := range plan.Containers {
+		entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		name := scalarNode(c.Info.Name)
+		if c.Info.Image != "" {
+			name.LineComment = "# image: " + c.Info.Image
+		}
+		entry.Content = append(entry.Content, scalarNode("name"), name)
+		if c.Info.WorkingDir != "" && c.Info.WorkingDir != "/" {
+			entry.Content = append(entry.Content, scalarNode("workdir"), scalarNode(c.Info.WorkingDir))
+		}
+		if len(c.Paths) > 0 {
+			entry.Content = append(entry.Content, scalarNode("paths"), stringMapNode(c.Paths))
+		}
+		if c.User != "" {
+			entry.Content = append(entry.Content, scalarNode("user"), scalarNode(c.User))
+		}
+		if len(c.Env) > 0 {
+			entry.Content = append(entry.Content, scalarNode("env"), stringMapNode(c.Env))
+		}
+		containers.Content = append(containers.Content, scalarNode(c.Logical), entry)
+	}
+	containersKey := scalarNode("containers")
+	containersKey.HeadComment = blankLineMarker + "\n# Logical container names mapped to the actual container names.\n# Commands run in their container's working directory."
+	root.Content = append(root.Content, containersKey, containers)
+
+	if len(plan.Presets) > 0 {
+		presetsNode := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, ps := range plan.Presets {
+			presetsNode.Content = append(presetsNode.Content, scalarNode(ps.Name),
+				&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{scalarNode("container"), scalarNode(ps.Container)}})
+		}
+		presetsKey := scalarNode("presets")
+		presetsKey.HeadComment = blankLineMarker + "\n# Built-in command sets. A command defined under commands overrides\n# the preset's command of the same name."
+		root.Content = append(root.Content, presetsKey, presetsNode)
+	}
+
+	commands := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, cmd := range plan.Commands {
+		entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		entry.Content = append(entry.Content,
+			scalarNode("container"), scalarNode(cmd.Container),
+			scalarNode("exec"), argvNode([]string{cmd.Tool}))
+		commands.Content = append(commands.Content, scalarNode(cmd.Tool), entry)
+	}
+	commandsKey := scalarNode("commands")
+	commandsKey.HeadComment = blankLineMarker + "\n" + commandsComment
+	if len(plan.Commands) == 0 {
+		commands.Style = yaml.FlowStyle
+		commandsKey.HeadComment += "\n# None found; add them with bridge config add-command."
+	}
+	root.Content = append(root.Content, commandsKey, commands)
+
+	d := &configDocument{doc: &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}, markers: true}
+	data, err := d.bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("generated config does not parse: %w", err)
+	}
+	config.expandPresets()
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("generated config is invalid: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func initFixture() []initContainer {
+	return []initContainer{
+		{Info: containerInfo{Name: "myproject-php-1", Image: "php:8.3-fpm", WorkingDir: "/var/www/html", Service: "php"}, Tools: []string{"php", "composer", "node", "npm"}},
+		{Info: containerInfo{Name: "myproject-node-1", Image: "node:20", WorkingDir: "/app", Service: "node"}, Tools: []string{"node", "npm", "npx", "python"}},
+		{Info: containerInfo{Name: "myproject-db-1", Image: "docker.io/library/postgres:16", Service: "db"}, Tools: []string{"psql"}},
+	}
+}
+
+func TestBuildInitPlan(t *testing.T) {
+	plan := buildInitPlan("myproject", initFixture())
+
+	got := make(map[string]string)
+	for _, cmd := range plan.Commands {
+		got[cmd.Tool] = cmd.Container
+	}
+	want := map[string]string{
+		"php":      "php",
+		"composer": "php",
+		"node":     "node",
+		"npm":      "node",
+		"npx":      "node",
+		"python":   "node",
+		"psql":     "db",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("routes = %v, want %v", got, want)
+	}
+	if plan.Commands[2].Tool != "node" || !reflect.DeepEqual(plan.Commands[2].Candidates, []string{"node", "php"}) {
+		t.Errorf("node command = %+v", plan.Commands[2])
+	}
+}
+
+func TestBuildInitPlanLogicalNames(t *testing.T) {
+	plan := buildInitPlan("", []initContainer{
+		{Info: containerInfo{Name: "a-php-1", Service: "php"}, Tools: []string{"php"}},
+		{Info: containerInfo{Name: "b-php-1", Service: "php"}, Tools: []string{"php"}},
+		{Info: containerInfo{Name: "standalone"}, Tools: []string{"go"}},
+	})
+
+	var names []string
+	for _, c := range plan.Containers {
+		names = append(names, c.Logical)
+	}
+	if want := []string{"php", "b-php-1", "standalone"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("logical names = %q, want %q", names, want)
+	}
+}
+
+func TestChooseCommands(t *testing.T) {
+	plan := buildInitPlan("myproject", initFixture())
+
+	// php: default; composer: skip; node: second candidate; npm: invalid then 1
+	var out bytes.Buffer
+	in := bufio.NewReader(strings.NewReader("\n0\n2\n9\n1\n"))
+	chooseCommands(in, &out, &plan)
+	plan.prune()
+
+	got := make(map[string]string)
+	for _, cmd := range plan.Commands {
+		got[cmd.Tool] = cmd.Container
+	}
+	want := map[string]string{"php": "php", "node": "php", "npm": "node", "npx": "node", "python": "node", "psql": "db"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("routes = %v, want %v", got, want)
+	}
+	if !contains(out.String(), "Route node to: 1) node, 2) php, 0) skip [1]: ") {
+		t.Errorf("prompt output = %q", out.String())
+	}
+	if !contains(out.String(), "Please answer a number from 0 to 2.") {
+		t.Errorf("expected a retry prompt, got %q", out.String())
+	}
+}
+
+func TestRenderInitConfig(t *testing.T) {
+	plan := buildInitPlan("myproject", initFixture())
+	for i := range plan.Commands {
+		if plan.Commands[i].Tool != "php" && plan.Commands[i].Tool != "psql" {
+			plan.Commands[i].Container = ""
+		}
+	}
+	plan.prune()
+
+	data, err := renderInitConfig(plan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "# Generated by `bridge config init` from the running containers\n" +
+		"# of compose project 'myproject'.\n" +
+		"# See examples/claude-bridge.yaml for everything bridge.yaml can do.\n" +
+		`version: "2"
+
+# Logical container names mapped to the actual container names.
+# Commands run in their container's working directory.
+containers:
+  db:
+    name: myproject-db-1 # image: docker.io/library/postgres:16
+  php:
+    name: myproject-php-1 # image: php:8.3-fpm
+    workdir: /var/www/html
+
+# Tools found on each container's PATH with ` + "`command -v`" + `.
+commands:
+  php:
+    container: php
+    exec: [php]
+  psql:
+    container: db
+    exec: [psql]
+`
+	if string(data) != want {
+		t.Errorf("config =\n%s\nwant\n%s", data, want)
+	}
+
+	if _, err := LoadConfig(writeConfig(t, string(data)), ""); err != nil {
+		t.Errorf("generated config does not load: %v", err)
+	}
+}
+
+func TestProbeInitPlan(t *testing.T) {
+	t.Setenv("COMPOSE_PROJECT_NAME", "myproject")
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("no hostname: %v", err)
+	}
+	self := `[{"Id": "self1", "Name": "/myproject-claude-1", "Config": {}, "Mounts": [
+	  {"Type": "bind", "Source": "/home/dev/myproject", "Destination": "/workspace"}]}]`
+	script := `for t in "$@"; do command -v "$t" >/dev/null 2>&1 && echo "$t"; done; true`
+	fakeDocker(t, map[string]string{
+		"inspect --type container " + hostname:                                             self,
+		"ps --quiet --no-trunc --filter label=com.docker.compose.project=myproject":        "abc123\n",
+		"ps --quiet --no-trunc --filter label=com.docker.compose.project=empty":            "",
+		"inspect --type container abc123":                                                  inspectPHP,
+		"exec myproject-php-1 sh -c " + script + " sh " + strings.Join(toolCatalogue, " "): "php\ncomposer\n",
+	})
+
+	plan, err := probeInitPlan("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.Project != "myproject" || len(plan.Containers) != 1 || len(plan.Commands) != 2 {
+		t.Errorf("plan = %+v", plan)
+	}
+	if want := map[string]string{"/workspace": "/var/www/html"}; len(plan.Containers) == 1 && !reflect.DeepEqual(plan.Containers[0].Paths, want) {
+		t.Errorf("paths = %v, want %v from the shared mount", plan.Containers[0].Paths, want)
+	}
+
+	if _, err := probeInitPlan("empty"); err == nil || !contains(err.Error(), "no running containers found in compose project 'empty'") {
+		t.Errorf("expected error for empty project, got %v", err)
+	}
+}
+
+func TestInitPlanUsePresets(t *testing.T) {
+	plan := buildInitPlan("myproject", initFixture())
+	if err := plan.usePresets([]string{"laravel"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plan.prune()
+
+	data, err := renderInitConfig(plan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"presets:\n  laravel:\n    container: php\n",
+		"commands:\n  node:\n",
+	} {
+		if !contains(string(data), want) {
+			t.Errorf("config does not contain %q:\n%s", want, data)
+		}
+	}
+	if contains(string(data), "  composer:") {
+		t.Errorf("composer should come from the preset:\n%s", data)
+	}
+
+	if err := plan.usePresets([]string{"rails"}); err == nil || !contains(err.Error(), "preset 'rails': no container provides ruby") {
+		t.Errorf("expected missing tool error, got %v", err)
+	}
+	if err := plan.usePresets([]string{"django"}); err == nil || !contains(err.Error(), "unknown preset 'django'") {
+		t.Errorf("expected unknown preset error, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// interpolator expands environment variables using Docker Compose semantics.
+type interpolator struct {
+	lookup func(name string) (string, bool)
+	unset  []string // Variables referenced without a default while unset
+}
+
+// interpolateNode expands environment variables in every scalar value of a
+// node tree. Mapping keys are left untouched, as in Docker Compose. Errors
+// and warnings are prefixed with file:line. Unset variables without a
+// default expand to an empty string and produce a warning.
+func interpolateNode(node *yaml.Node, file string, lookup func(string) (string, bool)) ([]string, error) {
+	var warnings []string
+
+	var walk func(n *yaml.Node) error
+	walk = func(n *yaml.Node) error {
+		switch n.Kind {
+		case yaml.ScalarNode:
+			if !strings.Contains(n.Value, "$") {
+				return nil
+			}
+			in := &interpolator{lookup: lookup}
+			value, err := in.expand(n.Value)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", file, n.Line, err)
+			}
+			for _, name := range in.unset {
+				warnings = append(warnings, fmt.Sprintf("%s:%d: variable '%s' is not set, defaulting to a blank string", file, n.Line, name))
+			}
+			n.Value = value
+		case yaml.MappingNode:
+			for i := 1; i < len(n.Content); i += 2 {
+				if err := walk(n.Content[i]); err != nil {
+					return err
+				}
+			}
+		default:
+			for _, child := range n.Content {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walk(node); err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// expand replaces $VAR, ${VAR} and the Compose parameter forms in s:
+//   - ${VAR:-default}  default if VAR is unset or empty
+//   - ${VAR-default}   default if VAR is unset
+//   - ${VAR:?message}  error if VAR is unset or empty
+//   - ${VAR?message}   error if VAR is unset
+//   - ${VAR:+alt}      alt if VAR is set and non-empty, otherwise empty
+//   - ${VAR+alt}       alt if VAR is set, otherwise empty
+//
+// Defaults, messages and alternatives may themselves contain variables.
+// "$$" is a literal "$".
+func (in *interpolator) expand(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		next := s[i+1]
+		switch {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("invalid interpolation format in %q: unterminated '${'", s)
+			}
+			value, err := in.evalExpression(s[i+2 : end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+		case isNameStart(next):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			b.WriteString(in.variable(s[i+1 : j]))
+			i = j - 1
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// variable returns the value of a plainly referenced variable, recording it
+// as unset if it has no value.
+func (in *interpolator) variable(name string) string {
+	value, ok := in.lookup(name)
+	if !ok {
+		in.unset = append(in.unset, name)
+	}
+	return value
+}
+
+// evalExpression evaluates the contents of a ${...} expression.
+func (in *interpolator) evalExpression(expr string) (string, error) {
+	n := 0
+	for n < len(expr) && isNameChar(expr[n]) && (n > 0 || isNameStart(expr[n])) {
+		n++
+	}
+	name, rest := expr[:n], expr[n:]
+	if name == "" {
+		return "", fmt.Errorf("invalid interpolation format: ${%s}", expr)
+	}
+	if rest == "" {
+		return in.variable(name), nil
+	}
+
+	value, set := in.lookup(name)
+	nonEmpty := set && value != ""
+
+	for _, op := range []string{":-", ":?", ":+", "-", "?", "+"} {
+		if !strings.HasPrefix(rest, op) {
+			continue
+		}
+		operand := rest[len(op):]
+		strict := op[0] == ':'
+		present := nonEmpty || (!strict && set)
+
+		switch op {
+		case ":-", "-":
+			if present {
+				return value, nil
+			}
+			return in.expand(operand)
+		case ":?", "?":
+			if present {
+				return value, nil
+			}
+			message, err := in.expand(operand)
+			if err != nil {
+				return "", err
+			}
+			if message == "" {
+				return "", fmt.Errorf("required variable '%s' is missing a value", name)
+			}
+			return "", fmt.Errorf("required variable '%s' is missing a value: %s", name, message)
+		case ":+", "+":
+			if present {
+				return in.expand(operand)
+			}
+			return "", nil
+		}
+	}
+	return "", fmt.Errorf("invalid interpolation format: ${%s}", expr)
+}
+
+// matchingBrace returns the index of the '}' closing the '{' at open, or -1.
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
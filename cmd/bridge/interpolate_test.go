@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	env := map[string]string{
+		"PROJECT": "shop",
+		"EMPTY":   "",
+		"MOUNT":   "/var/www/html",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		input         string
+		expected      string
+		unset         []string
+		errorContains string
+	}{
+		{input: "${PROJECT}-php-1", expected: "shop-php-1"},
+		{input: "$PROJECT-php-1", expected: "shop-php-1"},
+		{input: "no variables", expected: "no variables"},
+		{input: "$$PROJECT", expected: "$PROJECT"},
+		{input: "cost: 5$", expected: "cost: 5$"},
+		{input: "${MISSING}", expected: "", unset: []string{"MISSING"}},
+		{input: "$MISSING/x", expected: "/x", unset: []string{"MISSING"}},
+		{input: "${MISSING:-/app}", expected: "/app"},
+		{input: "${EMPTY:-/app}", expected: "/app"},
+		{input: "${EMPTY-/app}", expected: ""},
+		{input: "${MISSING-/app}", expected: "/app"},
+		{input: "${MISSING:-${MOUNT}/src}", expected: "/var/www/html/src"},
+		{input: "${PROJECT:+prefix-}x", expected: "prefix-x"},
+		{input: "${EMPTY:+prefix-}x", expected: "x"},
+		{input: "${EMPTY+prefix-}x", expected: "prefix-x"},
+		{input: "${MISSING+prefix-}x", expected: "x"},
+		{input: "${MOUNT:?mount is required}", expected: "/var/www/html"},
+		{input: "${EMPTY?must be set}", expected: ""},
+		{input: "${MISSING:?set COMPOSE_PROJECT_NAME}", errorContains: "required variable 'MISSING' is missing a value: set COMPOSE_PROJECT_NAME"},
+		{input: "${EMPTY:?}", errorContains: "required variable 'EMPTY' is missing a value"},
+		{input: "${PROJECT", errorContains: "unterminated"},
+		{input: "${}", errorContains: "invalid interpolation format"},
+		{input: "${PROJECT!x}", errorContains: "invalid interpolation format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			in := &interpolator{lookup: lookup}
+			got, err := in.expand(tt.input)
+			if tt.errorContains != "" {
+				if err == nil || !contains(err.Error(), tt.errorContains) {
+					t.Errorf("expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expand(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+			if len(in.unset) != len(tt.unset) {
+				t.Errorf("unset = %v, want %v", in.unset, tt.unset)
+			}
+		})
+	}
+}
+
+func TestLoadConfigInterpolation(t *testing.T) {
+	t.Setenv("COMPOSE_PROJECT_NAME", "shop")
+	t.Setenv("BRIDGE_PROFILE", "")
+
+	path := writeConfig(t, `version: "1"
+containers:
+  php: ${COMPOSE_PROJECT_NAME}-php-1
+commands:
+  php:
+    container: php
+    exec: php
+    workdir: ${APP_MOUNT:-/var/www/html}
+    paths:
+      /workspace: ${APP_MOUNT:-/var/www/html}
+`)
+	config, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if got := config.ResolveContainer("php"); got != "shop-php-1" {
+		t.Errorf("php resolved to %q, want shop-php-1", got)
+	}
+	if got := config.Commands["php"].Paths["/workspace"]; got != "/var/www/html" {
+		t.Errorf("paths[/workspace] = %q, want /var/www/html", got)
+	}
+
+	path = writeConfig(t, `version: "1"
+commands:
+  php:
+    container: ${PHP_CONTAINER:?set PHP_CONTAINER in .env}
+    exec: php
+`)
+	_, err = LoadConfig(path, "")
+	want := "invalid interpolation in " + path + ":4: required variable 'PHP_CONTAINER' is missing a value: set PHP_CONTAINER in .env"
+	if err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergedSections are top-level keys whose entries are merged individually
+// across layers. Any other top-level key is replaced wholesale.
+var mergedSections = map[string]bool{
+	"containers": true,
+	"commands":   true,
+	"scopes":     true,
+	"presets":    true,
+	"profiles":   true,
+}
+
+// layerPath is a config file candidate. Optional layers are skipped when
+// the file does not exist.
+type layerPath struct {
+	Path     string
+	Optional bool
+}
+
+// configLayer is a single config file combined with the fragments it
+// includes. Files lists every file that contributed, and nodeFiles maps
+// each node to the file that defined it.
+type configLayer struct {
+	Path      string
+	Root      *yaml.Node // Top-level mapping node
+	Files     []string
+	nodeFiles map[*yaml.Node]string
+}
+
+// origin returns "file:line" for a key node of this layer.
+func (l configLayer) origin(key *yaml.Node) string {
+	file := l.Path
+	if f, ok := l.nodeFiles[key]; ok {
+		file = f
+	}
+	return fmt.Sprintf("%s:%d", file, key.Line)
+}
+
+// loadedConfig is the merged result of all config layers.
+type loadedConfig struct {
+	Root    *yaml.Node // Merged top-level mapping node
+	Sources provenance // Origin of each top-level value and section entry
+	Files   []string   // Every file that contributed, lowest priority first
+
+	nodeFiles map[*yaml.Node]string
+}
+
+// userConfigPath returns the user-level config file path:
+// $XDG_CONFIG_HOME/bridge/bridge.yaml, or ~/.config/bridge/bridge.yaml.
+// Returns "" if neither location can be determined.
+func userConfigPath() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, "bridge", "bridge.yaml")
+}
+
+// localConfigPath returns the git-ignored local override file that sits next
+// to a project config (bridge.yaml -> bridge.local.yaml).
+func localConfigPath(projectPath string) string {
+	ext := filepath.Ext(projectPath)
+	return strings.TrimSuffix(projectPath, ext) + ".local" + ext
+}
+
+// configLayerPaths returns the config files to merge, lowest priority first.
+// An explicit path (--config, colon-separated) is used on its own. Otherwise
+// the layers are: user config, project config, project local config, then
+// each entry of the colon-separated BRIDGE_CONFIG.
+func configLayerPaths(configPath string) []layerPath {
+	var paths []layerPath
+	if configPath != "" {
+		for _, p := range filepath.SplitList(configPath) {
+			paths = append(paths, layerPath{Path: p})
+		}
+		return paths
+	}
+
+	if user := userConfigPath(); user != "" {
+		paths = append(paths, layerPath{Path: user, Optional: true})
+	}
+	project := getDefaultConfigPath()
+	paths = append(paths,
+		layerPath{Path: project, Optional: true},
+		layerPath{Path: localConfigPath(project), Optional: true},
+	)
+	for _, p := range filepath.SplitList(os.Getenv("BRIDGE_CONFIG")) {
+		if p != "" {
+			paths = append(paths, layerPath{Path: p})
+		}
+	}
+	return paths
+}
+
+// configReader supplies the contents of config files and the variables
+// their ${VAR} references expand to.
+type configReader interface {
+	read(path string) ([]byte, error)
+	lookupEnv(name string) (string, bool)
+}
+
+// fileOverrides supplies config file contents by absolute path in place of
+// the files on disk, so that an edit can be validated before it is written.
+type fileOverrides map[string][]byte
+
+// read returns the contents of path, preferring an override.
+func (o fileOverrides) read(path string) ([]byte, error) {
+	if abs, err := filepath.Abs(path); err == nil {
+		if data, ok := o[abs]; ok {
+			return data, nil
+		}
+	}
+	return os.ReadFile(path)
+}
+
+// lookupEnv reads the environment.
+func (o fileOverrides) lookupEnv(name string) (string, bool) {
+	return os.LookupEnv(name)
+}
+
+// readLayers reads every existing layer in order, together with its
+// includes and bridge.d fragments.
+// Returns an error if a required layer is missing or if no layer exists.
+func readLayers(paths []layerPath, files configReader) ([]configLayer, error) {
+	var layers []configLayer
+	for _, lp := range paths {
+		if _, err := os.Stat(lp.Path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				if lp.Optional {
+					continue
+				}
+				return nil, fmt.Errorf("config file not found: %s\nSee %s for an example configuration", lp.Path, exampleConfigPath)
+			}
+			return nil, fmt.Errorf("failed to read config file %s: %w", lp.Path, err)
+		}
+
+		layer, err := loadLayerFile(lp.Path, files)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("config file not found: %s\nSee %s for an example configuration", getDefaultConfigPath(), exampleConfigPath)
+	}
+	return layers, nil
+}
+
+// readConfigFile reads, parses and interpolates a single config file.
+func readConfigFile(path string, files configReader) (*yaml.Node, error) {
+	data, err := files.read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	root, err := parseLayer(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand ${VAR} references in values
+	warnings, err := interpolateNode(root, path, files.lookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interpolation in %w", err)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	return root, nil
+}
+
+// parseLayer parses file contents into a top-level mapping node.
+// An empty file yields an empty mapping.
+func parseLayer(path string, data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML in %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid YAML in %s: top level must be a mapping", path)
+	}
+	return root, nil
+}
+
+// isNull reports whether a node is an explicit YAML null (~ or null).
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// mappingIndex returns the index of key's key node in a mapping, or -1.
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mappingSet replaces or appends a key/value pair in a mapping.
+func mappingSet(mapping, key, value *yaml.Node) {
+	if i := mappingIndex(mapping, key.Value); i >= 0 {
+		mapping.Content[i], mapping.Content[i+1] = key, value
+		return
+	}
+	mapping.Content = append(mapping.Content, key, value)
+}
+
+// mappingDelete removes a key from a mapping if present.
+func mappingDelete(mapping *yaml.Node, key string) {
+	if i := mappingIndex(mapping, key); i >= 0 {
+		mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+	}
+}
+
+// provenance records which file and line defined each merged value, keyed
+// by "key" for top-level values, "section.entry" for section entries and
+// "section.entry.commands.name" for the commands of scopes and profiles.
+type provenance map[string]string
+
+// deleteEntry removes the origins of a section entry and of the values
+// nested in it.
+func (p provenance) deleteEntry(name string) {
+	delete(p, name)
+	for key := range p {
+		if strings.HasPrefix(key, name+".") {
+			delete(p, key)
+		}
+	}
+}
+
+// addNestedCommands records the origins of the commands of a scope or
+// profile entry.
+func (p provenance) addNestedCommands(name string, entry *yaml.Node, layer configLayer) {
+	if entry.Kind != yaml.MappingNode {
+		return
+	}
+	i := mappingIndex(entry, "commands")
+	if i < 0 || entry.Content[i+1].Kind != yaml.MappingNode {
+		return
+	}
+	commands := entry.Content[i+1]
+	for j := 0; j+1 < len(commands.Content); j += 2 {
+		p[name+".commands."+commands.Content[j].Value] = layer.origin(commands.Content[j])
+	}
+}
+
+// mergeLayers merges config layers in order into a single mapping node.
+//
+// Merge rules:
+//   - Top-level scalars and lists: a later layer replaces the earlier value
+//   - containers, commands, scopes, profiles: merged per entry; a later
+//     entry replaces the earlier entry of the same name entirely
+//   - An entry (or top-level key) set to null deletes the inherited value
+func mergeLayers(layers []configLayer) (*yaml.Node, provenance) {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	sources := provenance{}
+
+	for _, layer := range layers {
+		root := layer.Root
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i], root.Content[i+1]
+
+			if isNull(value) {
+				mappingDelete(merged, key.Value)
+				sources.deleteEntry(key.Value)
+				continue
+			}
+
+			if !mergedSections[key.Value] || value.Kind != yaml.MappingNode {
+				mappingSet(merged, key, value)
+				sources[key.Value] = layer.origin(key)
+				continue
+			}
+
+			// Merge section entries into a fresh mapping so that later
+			// layers never modify an earlier layer's nodes
+			var section *yaml.Node
+			if idx := mappingIndex(merged, key.Value); idx >= 0 && merged.Content[idx+1].Kind == yaml.MappingNode {
+				section = merged.Content[idx+1]
+			} else {
+				section = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: value.Line, Column: value.Column}
+				mappingSet(merged, key, section)
+				sources[key.Value] = layer.origin(key)
+			}
+
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				entryKey, entryValue := value.Content[j], value.Content[j+1]
+				name := key.Value + "." + entryKey.Value
+				sources.deleteEntry(name)
+				if isNull(entryValue) {
+					mappingDelete(section, entryKey.Value)
+					continue
+				}
+				mappingSet(section, entryKey, entryValue)
+				sources[name] = layer.origin(entryKey)
+				if key.Value == "scopes" || key.Value == "profiles" {
+					sources.addNestedCommands(name, entryValue, layer)
+				}
+			}
+		}
+	}
+
+	return merged, sources
+}
+
+// loadLayers resolves, reads and merges the config layers for configPath.
+func loadLayers(configPath string) (*loadedConfig, error) {
+	return loadLayersWith(configPath, fileOverrides(nil))
+}
+
+// loadLayersWith is loadLayers with the files' contents supplied by files.
+func loadLayersWith(configPath string, files configReader) (*loadedConfig, error) {
+	layers, err := readLayers(configLayerPaths(configPath), files)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := &loadedConfig{nodeFiles: make(map[*yaml.Node]string)}
+	for _, layer := range layers {
+		loaded.Files = append(loaded.Files, layer.Files...)
+		for node, file := range layer.nodeFiles {
+			loaded.nodeFiles[node] = file
+		}
+	}
+	loaded.Root, loaded.Sources = mergeLayers(layers)
+	fillNodeFiles(loaded.Root, loaded.nodeFiles)
+	return loaded, nil
+}
+
+// fillNodeFiles assigns nodes created during merging the file of their
+// first child that has one.
+func fillNodeFiles(node *yaml.Node, nodeFiles map[*yaml.Node]string) string {
+	file, ok := nodeFiles[node]
+	for _, child := range node.Content {
+		if f := fillNodeFiles(child, nodeFiles); !ok && f != "" {
+			file, ok = f, true
+			nodeFiles[node] = f
+		}
+	}
+	return file
+}
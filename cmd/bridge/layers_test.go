@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeLayers(t *testing.T) {
+	parse := func(path, content string) configLayer {
+		t.Helper()
+		root, err := parseLayer(path, []byte(content))
+		if err != nil {
+			t.Fatalf("parseLayer(%s): %v", path, err)
+		}
+		upgradeFile(root, "")
+		return configLayer{Path: path, Root: root, Files: []string{path}}
+	}
+
+	global := parse("global.yaml", `version: "1"
+default_container: app
+containers:
+  app: global-app
+  node: global-node
+commands:
+  npm:
+    container: node
+    exec: npm
+  yarn:
+    container: node
+    exec: yarn
+profiles:
+  ci:
+    commands:
+      npm:
+        exec: [npm, ci]
+      yarn:
+        exec: [yarn]
+`)
+	project := parse("project.yaml", `containers:
+  app: project-app
+commands:
+  npm:
+    container: node
+    exec: npm
+    workdir: /app
+  yarn: null
+scopes:
+  /srv/legacy:
+    commands:
+      npm:
+        container: node
+profiles:
+  ci:
+    commands:
+      npm:
+        exec: [npm, ci]
+`)
+	local := parse("local.yaml", `default_container: php
+containers:
+  node: ~
+`)
+
+	root, sources := mergeLayers([]configLayer{global, project, local})
+
+	var config Config
+	if err := root.Decode(&config); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if config.Version != "2" || config.DefaultContainer != "php" {
+		t.Errorf("scalars: version=%q default_container=%q", config.Version, config.DefaultContainer)
+	}
+	if config.Containers["app"].Name != "project-app" {
+		t.Errorf("containers.app = %q, want project-app", config.Containers["app"].Name)
+	}
+	if _, ok := config.Containers["node"]; ok {
+		t.Errorf("containers.node should be deleted by null: %v", config.Containers)
+	}
+	if config.Commands["npm"].Workdir != "/app" {
+		t.Errorf("commands.npm not replaced by project layer: %+v", config.Commands["npm"])
+	}
+	if _, ok := config.Commands["yarn"]; ok {
+		t.Errorf("commands.yarn should be deleted by null: %v", config.Commands)
+	}
+
+	expected := map[string]string{
+		"version":           "global.yaml:1",
+		"default_container": "local.yaml:1",
+		"containers.app":    "project.yaml:2",
+		"commands.npm":      "project.yaml:4",
+
+		"scopes./srv/legacy.commands.npm": "project.yaml:12",
+		"profiles.ci.commands.npm":        "project.yaml:17",
+	}
+	for key, want := range expected {
+		if sources[key] != want {
+			t.Errorf("sources[%q] = %q, want %q", key, sources[key], want)
+		}
+	}
+	if _, ok := sources["commands.yarn"]; ok {
+		t.Error("deleted command should have no provenance")
+	}
+	if _, ok := sources["profiles.ci.commands.yarn"]; ok {
+		t.Error("commands of a replaced profile should have no provenance")
+	}
+
+	// Earlier layers must not be modified by the merge
+	var globalConfig Config
+	if err := global.Root.Decode(&globalConfig); err != nil {
+		t.Fatalf("Decode global: %v", err)
+	}
+	if globalConfig.Containers["app"].Name != "global-app" || len(globalConfig.Commands) != 2 {
+		t.Errorf("global layer was modified: %+v", globalConfig)
+	}
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	extra := filepath.Join(t.TempDir(), "extra.yaml")
+
+	files := map[string]string{
+		filepath.Join(home, "bridge", "bridge.yaml"): `version: "1"
+containers:
+  node: user-node
+commands:
+  node:
+    container: node
+    exec: node
+`,
+		filepath.Join(project, "bridge.yaml"): `commands:
+  npm:
+    container: node
+    exec: npm
+`,
+		filepath.Join(project, "bridge.local.yaml"): `containers:
+  node: local-node
+`,
+		extra: `commands:
+  node: null
+`,
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("SIDECAR_CONFIG_DIR", project)
+	t.Setenv("BRIDGE_CONFIG", extra)
+	t.Setenv("BRIDGE_PROFILE", "")
+
+	config, err := LoadConfig("", "")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(config.Sources) != 4 {
+		t.Errorf("Sources = %v, want 4 layers", config.Sources)
+	}
+	if config.ResolveContainer("node") != "local-node" {
+		t.Errorf("node resolved to %q, want local-node", config.ResolveContainer("node"))
+	}
+	if _, ok := config.Commands["node"]; ok {
+		t.Error("node command should be deleted by BRIDGE_CONFIG layer")
+	}
+	if _, ok := config.Commands["npm"]; !ok {
+		t.Error("npm command missing from project layer")
+	}
+
+	// Explicit --config bypasses layering
+	config, err = LoadConfig(filepath.Join(home, "bridge", "bridge.yaml"), "")
+	if err != nil {
+		t.Fatalf("LoadConfig explicit: %v", err)
+	}
+	if len(config.Sources) != 1 || config.ResolveContainer("node") != "user-node" {
+		t.Errorf("explicit config: sources=%v node=%q", config.Sources, config.ResolveContainer("node"))
+	}
+}
+
+func TestLoadConfigLayersMissing(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("SIDECAR_CONFIG_DIR", t.TempDir())
+
+	t.Setenv("BRIDGE_CONFIG", "")
+	_, err := LoadConfig("", "")
+	if err == nil || !contains(err.Error(), "config file not found") {
+		t.Errorf("expected not found error with no layers, got %v", err)
+	}
+
+	t.Setenv("BRIDGE_CONFIG", "/nonexistent/bridge.yaml")
+	_, err = LoadConfig("", "")
+	if err == nil || !contains(err.Error(), "config file not found: /nonexistent/bridge.yaml") {
+		t.Errorf("expected missing BRIDGE_CONFIG error, got %v", err)
+	}
+}
+
+func TestShowConfigResolved(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	override := filepath.Join(dir, "override.yaml")
+	if err := os.WriteFile(base, []byte("# comment\nversion: \"1\"\ncommands:\n  go:\n    container: golang\n    exec: go\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(override, []byte("commands:\n  gofmt:\n    container: golang\n    exec: gofmt\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := showConfig(&buf, base+string(os.PathListSeparator)+override, true); err != nil {
+		t.Fatalf("showConfig: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"#   " + override,
+		`version: "2" # from ` + base + ":2",
+		"go: # from " + base + ":4",
+		"gofmt: # from " + override + ":2",
+	} {
+		if !contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if contains(out, "# comment") {
+		t.Errorf("source comments should be stripped:\n%s", out)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// routeEntry is one row of the routing table `bridge list` prints: a
+// command as it resolves in the current directory.
+type routeEntry struct {
+	Command       string            `json:"command"`
+	Container     string            `json:"container"`
+	ContainerName string            `json:"container_name"`
+	Exec          []string          `json:"exec"`
+	Workdir       string            `json:"workdir"`
+	User          string            `json:"user,omitempty"`
+	Paths         map[string]string `json:"paths,omitempty"`
+	AutoPaths     bool              `json:"auto_paths,omitempty"`
+	Source        string            `json:"source"`
+	Error         string            `json:"error,omitempty"`
+	Warnings      []string          `json:"warnings,omitempty"`
+
+	// Set by --check
+	Status     string `json:"status,omitempty"`      // running, stopped, not found or unknown
+	ExecFound  *bool  `json:"exec_found,omitempty"`  // Whether exec[0] exists in the container
+	CheckError string `json:"check_error,omitempty"` // Why the status or exec is unknown
+}
+
+// ok reports whether a checked entry can run.
+func (e routeEntry) ok() bool {
+	return e.Error == "" && e.Status == "running" && e.ExecFound != nil && *e.ExecFound
+}
+
+// listCommand implements `bridge list`.
+func listCommand(config *Config, args []string) int {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "Print the routing table as JSON")
+	check := fs.Bool("check", false, "Check that each container is running and each exec binary exists")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	entries := routingTable(config, currentDir())
+	if *check {
+		checkRoutes(entries)
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entries); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return 1
+		}
+	} else {
+		writeRoutingTable(os.Stdout, entries, *check)
+	}
+
+	if *check {
+		for _, e := range entries {
+			if !e.ok() {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// routingTable resolves every command visible from cwd the way runCommand
+// does before it builds the argument list: scope lookup, toolchain
+// selection, container defaults and container name. Routes depend on the
+// arguments and are not applied; paths: auto is left unresolved.
+func routingTable(config *Config, cwd string) []routeEntry {
+	names := make(map[string]bool)
+	for name := range config.Commands {
+		names[name] = true
+	}
+	for _, prefix := range config.ScopesFor(cwd) {
+		for name := range config.Scopes[prefix].Commands {
+			names[name] = true
+		}
+	}
+
+	entries := make([]routeEntry, 0, len(names))
+	for _, name := range sortedKeys(names) {
+		cmd, _ := config.LookupCommand(name, cwd)
+		scopePrefix, scope := config.commandScope(name, cwd)
+		entry := routeEntry{Command: name, Source: config.commandSource(name, scopePrefix, scope)}
+
+		resolved, _, ignored, err := cmd.resolveToolchainHint(cwd)
+		entry.Warnings = ignored
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			cmd = resolved
+		}
+		cmd = config.ApplyContainerDefaults(cmd)
+
+		entry.Container = cmd.Container
+		entry.ContainerName = config.ResolveContainer(cmd.Container)
+		entry.Exec = cmd.Exec
+		entry.User = cmd.User
+		entry.AutoPaths = cmd.Paths.Auto()
+		if paths := cmd.Paths.explicit(); len(paths) > 0 {
+			entry.Paths = paths
+		}
+		cmd.Paths = cmd.Paths.explicit()
+		entry.Workdir = determineWorkdir(&cmd)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// commandSource returns where a command visible from the current directory
+// was defined, as file:line.
+func (c *Config) commandSource(name, scopePrefix string, scope *Scope) string {
+	if scope != nil {
+		if _, ok := scope.Commands[name]; ok {
+			return c.Origins["scopes."+scopePrefix+".commands."+name]
+		}
+	}
+	if c.ActiveProfile != "" {
+		if _, ok := c.Profiles[c.ActiveProfile].Commands[name]; ok {
+			return c.Origins["profiles."+c.ActiveProfile+".commands."+name]
+		}
+	}
+	if origin, ok := c.Origins["commands."+name]; ok {
+		return origin
+	}
+	for _, preset := range sortedKeys(c.Presets) {
+		for _, pc := range presetCommandNames(preset) {
+			if pc == name {
+				return fmt.Sprintf("preset '%s' (%s)", preset, c.Origins["presets."+preset])
+			}
+		}
+	}
+	return ""
+}
+
+// checkRoutes records, for each entry, whether its container is running
+// and whether its exec binary exists there. Binaries are looked up in the
+// command's workdir and as its user, batched per container.
+func checkRoutes(entries []routeEntry) {
+	status := make(map[string]string)
+	checkErrs := make(map[string]error)
+	for _, e := range entries {
+		if _, ok := status[e.ContainerName]; ok || e.ContainerName == "" {
+			continue
+		}
+		containers, err := inspectContainers(e.ContainerName)
+		switch {
+		case err != nil && strings.Contains(strings.ToLower(err.Error()), "no such"):
+			status[e.ContainerName] = "not found"
+		case err != nil || len(containers) == 0:
+			status[e.ContainerName] = "unknown"
+			checkErrs[e.ContainerName] = err
+		case containers[0].Status == "running":
+			status[e.ContainerName] = "running"
+		default:
+			status[e.ContainerName] = "stopped"
+		}
+	}
+
+	type probe struct{ container, workdir, user string }
+	tools := make(map[probe][]string)
+	for _, e := range entries {
+		if status[e.ContainerName] == "running" && len(e.Exec) > 0 {
+			p := probe{e.ContainerName, e.Workdir, e.User}
+			if !slices.Contains(tools[p], e.Exec[0]) {
+				tools[p] = append(tools[p], e.Exec[0])
+			}
+		}
+	}
+	found := make(map[probe]map[string]bool)
+	probeErrs := make(map[probe]error)
+	for p, list := range tools {
+		present, err := probeToolsAs(p.container, p.workdir, p.user, list)
+		if err != nil {
+			probeErrs[p] = err
+			continue
+		}
+		found[p] = make(map[string]bool)
+		for _, tool := range present {
+			found[p][tool] = true
+		}
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		e.Status = status[e.ContainerName]
+		if e.Status == "" {
+			e.Status = "unknown"
+		}
+		if err := checkErrs[e.ContainerName]; err != nil {
+			e.CheckError = err.Error()
+		}
+		if len(e.Exec) == 0 {
+			continue
+		}
+		p := probe{e.ContainerName, e.Workdir, e.User}
+		if present, ok := found[p]; ok {
+			exists := present[e.Exec[0]]
+			e.ExecFound = &exists
+		} else if err := probeErrs[p]; err != nil {
+			e.CheckError = err.Error()
+		}
+	}
+}
+
+// writeRoutingTable prints the entries as an aligned table.
+func writeRoutingTable(w io.Writer, entries []routeEntry, checked bool) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	header := "COMMAND\tCONTAINER\tEXEC\tWORKDIR\tPATHS\tSOURCE"
+	if checked {
+		header += "\tSTATUS"
+	}
+	fmt.Fprintln(tw, header)
+
+	for _, e := range entries {
+		container := e.ContainerName
+		if e.Container != "" && e.Container != e.ContainerName {
+			container = e.Container + " (" + e.ContainerName + ")"
+		}
+		var paths []string
+		for _, host := range sortedKeys(e.Paths) {
+			paths = append(paths, host+":"+e.Paths[host])
+		}
+		if e.AutoPaths {
+			paths = append(paths, autoPaths)
+		}
+		row := []string{e.Command, container, strings.Join(e.Exec, " "), e.Workdir, orDash(strings.Join(paths, ", ")), orDash(e.Source)}
+		if checked {
+			row = append(row, e.checkSummary())
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+
+	for _, e := range entries {
+		if e.Error != "" {
+			fmt.Fprintf(w, "Error: command '%s': %s\n", e.Command, e.Error)
+		}
+		for _, warning := range e.Warnings {
+			fmt.Fprintf(w, "Warning: command '%s': %s\n", e.Command, warning)
+		}
+		if e.CheckError != "" {
+			fmt.Fprintf(w, "Warning: command '%s': check failed: %s\n", e.Command, e.CheckError)
+		}
+	}
+}
+
+// checkSummary describes the --check result of an entry.
+func (e routeEntry) checkSummary() string {
+	switch {
+	case e.Status != "running":
+		return "container " + e.Status
+	case e.ExecFound == nil:
+		return "exec unknown"
+	case !*e.ExecFound:
+		return "exec not found"
+	default:
+		return "ok"
+	}
+}
+
+// orDash returns s, or "-" if it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
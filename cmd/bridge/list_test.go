@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func listFixture(dir string) *Config {
+	return &Config{
+		Containers: map[string]ContainerConfig{
+			"php":  {Name: "shop-php-1", Workdir: "/var/www/html", User: "www-data"},
+			"node": {Name: "shop-node-1", Paths: PathMap{dir: "/app"}},
+		},
+		Commands: map[string]Command{
+			"php":  {Container: "php", Exec: []string{"php"}},
+			"npm":  {Container: "node", Exec: []string{"npm"}},
+			"node": {Container: "node", Exec: []string{"node"}, Paths: PathMap{autoPathsKey: autoPaths}},
+		},
+		Scopes: map[string]Scope{
+			filepath.Join(dir, "legacy"): {Commands: map[string]Command{
+				"php": {Container: "legacy", Exec: []string{"php7"}},
+			}},
+		},
+		Presets: map[string]PresetParams{"go": {Container: "php"}},
+		Origins: map[string]string{
+			"commands.php":                           "bridge.yaml:10",
+			"commands.npm":                           "bridge.yaml:13",
+			"commands.node":                          "bridge.d/node.yaml:2",
+			"presets.go":                             "bridge.yaml:20",
+			"scopes." + filepath.Join(dir, "legacy"): "bridge.yaml:25",
+
+			"scopes." + filepath.Join(dir, "legacy") + ".commands.php": "bridge.yaml:27",
+		},
+	}
+}
+
+func TestRoutingTable(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	config := listFixture(dir)
+	config.Commands["go"] = Command{Container: "php", Exec: []string{"go"}}
+
+	entries := routingTable(config, dir)
+	want := []routeEntry{
+		{Command: "go", Container: "php", ContainerName: "shop-php-1", Exec: []string{"go"}, Workdir: "/var/www/html", User: "www-data", Source: "preset 'go' (bridge.yaml:20)"},
+		{Command: "node", Container: "node", ContainerName: "shop-node-1", Exec: []string{"node"}, Workdir: dir, AutoPaths: true, Source: "bridge.d/node.yaml:2"},
+		{Command: "npm", Container: "node", ContainerName: "shop-node-1", Exec: []string{"npm"}, Workdir: "/app", Paths: map[string]string{dir: "/app"}, Source: "bridge.yaml:13"},
+		{Command: "php", Container: "php", ContainerName: "shop-php-1", Exec: []string{"php"}, Workdir: "/var/www/html", User: "www-data", Source: "bridge.yaml:10"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("routingTable =\n%+v\nwant\n%+v", entries, want)
+	}
+
+	// A scope's commands take precedence below its prefix
+	legacy := filepath.Join(dir, "legacy")
+	entries = routingTable(config, legacy)
+	php := entries[len(entries)-1]
+	if php.ContainerName != "legacy" || php.Source != "bridge.yaml:27" {
+		t.Errorf("scoped php = %+v, want container legacy from bridge.yaml:27", php)
+	}
+}
+
+func TestCheckRoutes(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	script := `for t in "$@"; do command -v "$t" >/dev/null 2>&1 && echo "$t"; done; true`
+	calls := fakeDocker(t, map[string]string{
+		"inspect --type container shop-php-1":                                         `[{"Id": "a", "Name": "/shop-php-1", "State": {"Status": "running"}}]`,
+		"inspect --type container shop-node-1":                                        `[{"Id": "b", "Name": "/shop-node-1", "State": {"Status": "exited"}}]`,
+		"exec -w /var/www/html -u www-data shop-php-1 sh -c " + script + " sh go php": "php\n",
+	})
+
+	config := listFixture(dir)
+	config.Commands["go"] = Command{Container: "php", Exec: []string{"go"}}
+	config.Commands["rake"] = Command{Container: "ruby", Exec: []string{"rake"}}
+	entries := routingTable(config, dir)
+	checkRoutes(entries)
+
+	var buf bytes.Buffer
+	writeRoutingTable(&buf, entries, true)
+	want := `COMMAND  CONTAINER                EXEC  WORKDIR        PATHS              SOURCE                        STATUS
+go       php (shop-php-1)         go    /var/www/html  -                  preset 'go' (bridge.yaml:20)  exec not found
+node     node (shop-node-1)       node  ` + dir + `  auto               bridge.d/node.yaml:2          container stopped
+npm      node (shop-node-1)       npm   /app           ` + dir + `:/app  bridge.yaml:13                container stopped
+php      php (shop-php-1)         php   /var/www/html  -                  bridge.yaml:10                ok
+rake     ruby                     rake  ` + dir + `  -                  -                             container not found
+`
+	if got := buf.String(); !equalColumns(got, want) {
+		t.Errorf("table =\n%s\nwant\n%s", got, want)
+	}
+
+	// Each container is inspected once
+	inspects := 0
+	for _, call := range *calls {
+		if contains(call, "inspect --type container shop-php-1") {
+			inspects++
+		}
+	}
+	if inspects != 1 {
+		t.Errorf("shop-php-1 inspected %d times, want 1", inspects)
+	}
+}
+
+// equalColumns compares tables ignoring column padding, which depends on
+// the temporary directory's length.
+func equalColumns(got, want string) bool {
+	return reflect.DeepEqual(splitColumns(got), splitColumns(want))
+}
+
+func splitColumns(table string) [][]string {
+	var rows [][]string
+	for _, line := range bytes.Split([]byte(table), []byte("\n")) {
+		var row []string
+		for _, field := range bytes.Split(line, []byte("  ")) {
+			if f := string(bytes.TrimSpace(field)); f != "" {
+				row = append(row, f)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
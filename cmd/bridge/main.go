@@ -1,11 +1,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"syscall"
 
 	"golang.org/x/term"
@@ -13,12 +17,25 @@ import (
 
 const version = "0.1.0"
 
+// reservedCommands are bridge subcommands. A configured command with the same
+// name is not run by `bridge <name>`; it runs through its wrapper symlink or
+// as `bridge -- <name>`, which skips subcommand dispatch.
+var reservedCommands = map[string]bool{
+	"config":  true,
+	"compose": true,
+	"list":    true,
+	"explain": true,
+	"doctor":  true,
+}
+
 func main() {
 	var (
 		showHelp     bool
 		showVersion  bool
 		configPath   string
+		profile      string
 		initWrappers string
+		jsonFlag     bool
 	)
 
 	flag.BoolVar(&showHelp, "help", false, "Show this help message")
@@ -27,7 +44,9 @@ func main() {
 	flag.BoolVar(&showVersion, "v", false, "Show version (shorthand)")
 	flag.StringVar(&configPath, "config", "", "Path to bridge config file")
 	flag.StringVar(&configPath, "c", "", "Path to bridge config file (shorthand)")
+	flag.StringVar(&profile, "profile", "", "Config profile to apply (overrides BRIDGE_PROFILE)")
 	flag.StringVar(&initWrappers, "init-wrappers", "", "Generate dispatcher symlinks in specified directory")
+	flag.BoolVar(&jsonFlag, "json", false, "Capture the run and print its result as JSON (same as BRIDGE_OUTPUT=json)")
 
 	flag.Usage = printUsage
 	flag.Parse()
@@ -42,20 +61,53 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Arguments after "--" are always a command to route, never a bridge
+	// subcommand. Wrappers use this so commands named like a subcommand
+	// still reach their container.
+	commandOnly := afterSeparator(os.Args[1:], flag.NArg())
+
+	// Handle bridge subcommands before loading config, so they can report
+	// on configs that fail to load
+	if args := flag.Args(); len(args) > 0 && !commandOnly {
+		switch args[0] {
+		case "config":
+			os.Exit(configCommand(args[1:], configPath))
+		case "doctor":
+			os.Exit(doctorCommand(args[1:], configPath, profile))
+		}
+	}
+
 	// Load config
-	config, err := LoadConfig(configPath)
+	args := flag.Args()
+	jsonMode := jsonOutput(jsonFlag) && initWrappers == "" && len(args) > 0 && (commandOnly || !reservedCommands[args[0]])
+	config, err := LoadConfig(configPath, profile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		if jsonMode {
+			configErrorResult(args, err).write(os.Stdout)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		}
 		os.Exit(1)
 	}
 
+	// Handle bridge subcommands that work on the loaded config
+	if args := flag.Args(); len(args) > 0 && !commandOnly {
+		switch args[0] {
+		case "compose":
+			os.Exit(composeCommand(config, configPath, args[1:]))
+		case "list":
+			os.Exit(listCommand(config, args[1:]))
+		case "explain":
+			os.Exit(explainCommand(config, args[1:]))
+		}
+	}
+
 	// Handle --init-wrappers flag
 	if initWrappers != "" {
 		exitCode := initWrappersCommand(config, initWrappers)
 		os.Exit(exitCode)
 	}
 
-	args := flag.Args()
 	if len(args) == 0 {
 		fmt.Fprintln(os.Stderr, "Error: no command specified")
 		fmt.Fprintln(os.Stderr, "Run 'bridge --help' for usage")
@@ -63,76 +115,220 @@ func main() {
 	}
 
 	// Route and execute the command
+	if jsonMode {
+		os.Exit(runCommandJSON(config, args, os.Stdout))
+	}
 	exitCode := runCommand(config, args)
 	os.Exit(exitCode)
 }
 
+// afterSeparator reports whether the last rest arguments of argv follow a
+// "--" separator, which the flag package drops from flag.Args.
+func afterSeparator(argv []string, rest int) bool {
+	i := len(argv) - rest - 1
+	return i >= 0 && argv[i] == "--"
+}
+
 // runCommand routes and executes the given command based on config.
 // Returns the exit code from the executed command.
 func runCommand(config *Config, args []string) int {
+	inv, err := routeCommand(config, args, debugTracer())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitCode(err)
+	}
+	if inv.Native != "" {
+		return execNative(inv.Native, args)
+	}
+
+	// Execute docker command
+	dockerCmd := exec.Command("docker", inv.dockerArgs()...)
+	dockerCmd.Stdin = os.Stdin
+	dockerCmd.Stdout = os.Stdout
+	dockerCmd.Stderr = os.Stderr
+
+	err = dockerCmd.Run()
+	if err != nil {
+		// Check for exit error to get exit code
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode()
+		}
+		// Other error (docker not found, etc.)
+		fmt.Fprintf(os.Stderr, "Error: failed to execute docker: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// invocation is a command resolved by routeCommand: either a native binary
+// to exec, or a docker exec in a container.
+type invocation struct {
+	Command   string            // Name the command was invoked as
+	Native    string            // Path of the native binary, for unconfigured commands
+	Container string            // Actual container name
+	Exec      []string          // Executable and its fixed arguments
+	Args      []string          // Arguments after expansion and path translation
+	Workdir   string            // Working directory in the container
+	User      string            // User to run as, if configured
+	Env       map[string]string // Environment passed with -e
+	TTY       bool              // Allocate a TTY (-t)
+}
+
+// dockerArgs returns the docker CLI arguments that run the invocation.
+func (inv *invocation) dockerArgs() []string {
+	// Use -i for interactive mode (keeps stdin open)
+	// Use -t for TTY allocation when both stdin and stdout are terminals (for colored output)
+	args := []string{"exec", "-i"}
+	if inv.TTY {
+		args = append(args, "-t")
+	}
+	args = append(args, "-w", inv.Workdir)
+
+	// Run as the configured user, if any
+	if inv.User != "" {
+		args = append(args, "-u", inv.User)
+	}
+
+	// Pass command environment variables (sorted for deterministic ordering)
+	args = append(args, envArgs(inv.Env)...)
+
+	args = append(args, inv.Container)
+	args = append(args, inv.Exec...)
+	return append(args, inv.Args...)
+}
+
+// routeError is a failure to route a command, with the exit code the
+// bridge exits with.
+type routeError struct {
+	code int
+	msg  string
+}
+
+func (e *routeError) Error() string { return e.msg }
+
+// exitCode returns the exit code for a routeCommand error.
+func exitCode(err error) int {
+	var re *routeError
+	if errors.As(err, &re) {
+		return re.code
+	}
+	return 1
+}
+
+// routeCommand resolves the given command line to an invocation, reporting
+// each decision to t: command lookup (directory scopes take precedence),
+// toolchain selection, argument routes, container defaults, container name,
+// path mappings, argument expansion and translation, and the workdir.
+func routeCommand(config *Config, args []string, t *tracer) (*invocation, error) {
 	cmdName := args[0]
 	cmdArgs := args[1:]
+	t.printf("config: %s", strings.Join(config.Sources, ", "))
+	if config.ActiveProfile != "" {
+		t.printf("profile: %s", config.ActiveProfile)
+	}
 
-	// Look up command in config
-	cmd, found := config.Commands[cmdName]
+	// Look up command in config (directory scopes take precedence)
+	cwd := currentDir()
+	cmd, found := config.LookupCommand(cmdName, cwd)
 
 	if !found {
 		// Command not in config and no override - fall through to native lookup
 		nativePath, err := exec.LookPath(cmdName)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: command '%s' not found in config and not available natively\n", cmdName)
-			return 127 // Standard "command not found" exit code
+			t.printf("command: '%s' is not configured and not on PATH", cmdName)
+			// Standard "command not found" exit code
+			return nil, &routeError{code: 127, msg: fmt.Sprintf("command '%s' not found in config and not available natively", cmdName)}
 		}
-		return execNative(nativePath, args)
+		t.printf("command: '%s' is not configured; running %s natively", cmdName, nativePath)
+		return &invocation{Command: cmdName, Native: nativePath, Args: cmdArgs}, nil
+	}
+	if scopePrefix, scope := config.commandScope(cmdName, cwd); scope != nil {
+		t.printf("command: '%s' from scope %s (%s)", cmdName, scopePrefix, orDash(config.commandSource(cmdName, scopePrefix, scope)))
+	} else {
+		t.printf("command: '%s' (%s)", cmdName, orDash(config.commandSource(cmdName, "", nil)))
 	}
 
-	// Resolve container name (apply containers mapping)
-	containerName := config.ResolveContainer(cmd.Container)
-
-	// Determine the actual executable (use exec if set, otherwise cmdName)
-	executable := cmd.Exec
-
-	// Translate path arguments
-	translatedArgs := cmd.TranslateArgs(cmdArgs)
+	// Pick a container by project toolchain version (select_by)
+	cmd, hint, ignored, err := cmd.resolveToolchainHint(cwd)
+	for _, msg := range ignored {
+		t.printf("toolchain: %s", msg)
+	}
+	if err != nil {
+		t.printf("toolchain: %s", err)
+		return nil, fmt.Errorf("command '%s': %s", cmdName, err)
+	}
+	if cmd.SelectBy != nil {
+		if hint != nil {
+			t.printf("toolchain: %s asks for %s %s; selected container '%s'", hint.Source, cmd.SelectBy.Tool, hint.Constraint, cmd.Container)
+		} else {
+			t.printf("toolchain: no %s version hint found from %s; using container '%s'", cmd.SelectBy.Tool, cwd, cmd.Container)
+		}
+	}
 
-	// Build docker exec command
-	// Use -i for interactive mode (keeps stdin open)
-	// Use -t for TTY allocation when both stdin and stdout are terminals (for colored output)
-	dockerArgs := []string{"exec", "-i"}
-	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
-		dockerArgs = append(dockerArgs, "-t")
+	// Apply the first matching argument route, if any
+	var route int
+	cmd, route = cmd.ResolveRoute(cmdArgs)
+	if route >= 0 {
+		t.printf("route: arguments match route %d", route)
+	} else if len(cmd.Routes) > 0 {
+		t.printf("route: no route matches the arguments; using the base definition")
 	}
 
-	// Determine working directory for docker exec
-	// Priority: 1) Translated CWD, 2) Static workdir from config, 3) Current CWD
-	workdir := determineWorkdir(&cmd)
-	dockerArgs = append(dockerArgs, "-w", workdir)
+	// Fill unset fields from the container's defaults
+	before := cmd
+	cmd = config.ApplyContainerDefaults(cmd)
+	if filled := filledDefaults(before, cmd); len(filled) > 0 {
+		t.printf("defaults: %s from container '%s'", strings.Join(filled, ", "), cmd.Container)
+	}
 
-	// Add container name
-	dockerArgs = append(dockerArgs, containerName)
+	// Resolve container name (apply containers mapping)
+	containerName := config.ResolveContainer(cmd.Container)
+	if containerName != cmd.Container {
+		t.printf("container: '%s' is %s", cmd.Container, containerName)
+	} else {
+		t.printf("container: %s (not in containers, used as is)", containerName)
+	}
 
-	// Add the command and its arguments
-	dockerArgs = append(dockerArgs, executable)
-	dockerArgs = append(dockerArgs, translatedArgs...)
+	// Infer path mappings from the containers' mounts (paths: auto)
+	auto := cmd.Paths.Auto()
+	cmd.Paths = resolvePaths(cmd.Paths, containerName, !config.Trusted)
+	t.printf("paths: %s", describePaths(cmd.Paths, auto))
 
-	// Execute docker command
-	dockerCmd := exec.Command("docker", dockerArgs...)
-	dockerCmd.Stdin = os.Stdin
-	dockerCmd.Stdout = os.Stdout
-	dockerCmd.Stderr = os.Stderr
-
-	err := dockerCmd.Run()
+	// Expand template and fixed prefix/suffix args, then translate paths
+	builtArgs, err := cmd.BuildArgs(cmdArgs, cwd)
 	if err != nil {
-		// Check for exit error to get exit code
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return exitErr.ExitCode()
+		t.printf("args: %s", err)
+		return nil, fmt.Errorf("command '%s': %s", cmdName, err)
+	}
+	if !slices.Equal(builtArgs, cmdArgs) {
+		t.printf("args: expanded to %s", shellJoin(builtArgs))
+	}
+	translatedArgs := cmd.TranslateArgs(builtArgs)
+	for i, arg := range builtArgs {
+		if source, _, ok := cmd.pathMapping(arg); ok {
+			t.printf("arg %d: %s -> %s (mapping %s)", i+1, arg, translatedArgs[i], source)
+		} else if t.enabled() && len(cmd.Paths) > 0 {
+			t.printf("arg %d: %s (no mapping)", i+1, arg)
 		}
-		// Other error (docker not found, etc.)
-		fmt.Fprintf(os.Stderr, "Error: failed to execute docker: %s\n", err)
-		return 1
 	}
 
-	return 0
+	// Determine working directory for docker exec
+	workdir, reason := chooseWorkdir(&cmd)
+	t.printf("workdir: %s (%s)", workdir, reason)
+
+	inv := &invocation{
+		Command:   cmdName,
+		Container: containerName,
+		Exec:      cmd.Exec,
+		Args:      translatedArgs,
+		Workdir:   workdir,
+		User:      cmd.User,
+		Env:       cmd.Env,
+		TTY:       term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())),
+	}
+	t.printf("run: docker %s", shellJoin(inv.dockerArgs()))
+	return inv, nil
 }
 
 // determineWorkdir determines the working directory to use for docker exec.
@@ -140,28 +336,49 @@ func runCommand(config *Config, args []string) int {
 //  2. Static workdir from config (if set and no mapping matched)
 //  3. Current CWD (as fallback)
 func determineWorkdir(cmd *Command) string {
+	workdir, _ := chooseWorkdir(cmd)
+	return workdir
+}
+
+// chooseWorkdir is determineWorkdir, also returning why the directory was
+// chosen.
+func chooseWorkdir(cmd *Command) (string, string) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		// If we can't get CWD, fall back to config workdir or root
 		if cmd.Workdir != "" {
-			return cmd.Workdir
+			return cmd.Workdir, "current directory unknown; using the configured workdir"
 		}
-		return "/"
+		return "/", "current directory unknown and no workdir configured"
 	}
 
 	// Try to translate the current working directory
-	translatedCwd, matched := cmd.TranslatePathWithMatch(cwd)
-
 	// If a path mapping matched, use the translated path (even if same as original)
-	if matched {
-		return translatedCwd
+	if source, _, ok := cmd.pathMapping(cwd); ok {
+		translated, _ := cmd.TranslatePathWithMatch(cwd)
+		return translated, fmt.Sprintf("current directory %s translated by mapping %s", cwd, source)
 	}
 
 	// No mapping matched - use static workdir if set, otherwise use CWD
 	if cmd.Workdir != "" {
-		return cmd.Workdir
+		return cmd.Workdir, fmt.Sprintf("no mapping matches the current directory %s; using the configured workdir", cwd)
+	}
+	return cwd, "no mapping matches the current directory and no workdir is configured"
+}
+
+// envArgs converts an env map into docker exec -e flags, sorted by key.
+func envArgs(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		args = append(args, "-e", k+"="+env[k])
 	}
-	return cwd
+	return args
 }
 
 // execNative executes a native binary using syscall.Exec, replacing the current process.
@@ -210,6 +427,11 @@ func initWrappers(config *Config, dir string) (int, int, error) {
 	for name := range config.Commands {
 		commandNames[name] = true
 	}
+	for _, scope := range config.Scopes {
+		for name := range scope.Commands {
+			commandNames[name] = true
+		}
+	}
 
 	created := 0
 	skipped := 0
@@ -252,21 +474,42 @@ func printUsage() {
 
 Usage:
   bridge [flags] <command> [args...]
+  bridge [flags] -- <command> [args...]
+                               Route <command> even if it is named like a bridge subcommand
   bridge --init-wrappers <dir>
+  bridge config <subcommand>   Inspect and manage configuration (see 'bridge config help')
+  bridge compose generate      Print a compose file with a sidecar per configured container
+  bridge list [--json] [--check]
+                               Show where each command is routed (--check verifies containers
+                               are running and exec binaries exist)
+  bridge explain <command> [args...]
+                               Show how a command would be routed, without running it
+  bridge doctor [--wrappers <dir>]
+                               Check Docker access, containers, exec binaries, paths and wrappers
 
 Flags:
-  -c, --config string        Path to bridge config file (default: $SIDECAR_CONFIG_DIR/bridge.yaml)
+  -c, --config string        Path to bridge config file (default: merged layers, see below)
+  --profile string           Config profile to apply (default: $BRIDGE_PROFILE)
   -h, --help                 Show this help message
   -v, --version              Show version
   --init-wrappers <dir>      Generate dispatcher symlinks in specified directory
+  --json                     Capture the run and print one JSON result (default: $BRIDGE_OUTPUT=json)
 
 Examples:
   bridge npm install           Run npm install in the default container
   bridge php artisan migrate   Run php artisan migrate in the PHP container
   bridge --config ./my.yaml npm test
+  bridge --profile ci npm test   Use the 'ci' profile's containers and commands
+  bridge --json npm test       Print npm's output, exit code and any error kind as JSON
   bridge --init-wrappers /scripts/wrappers   Generate symlinks at startup
 
-The bridge reads configuration from $SIDECAR_CONFIG_DIR/bridge.yaml (or BRIDGE_CONFIG env var).
-SIDECAR_CONFIG_DIR defaults to $PWD/.sidecar if not set.
+Configuration is merged from these layers (later layers override earlier ones):
+  1. ~/.config/bridge/bridge.yaml ($XDG_CONFIG_HOME/bridge/bridge.yaml)
+  2. $SIDECAR_CONFIG_DIR/bridge.yaml
+  3. $SIDECAR_CONFIG_DIR/bridge.local.yaml (git-ignored local overrides)
+  4. Each file in the colon-separated BRIDGE_CONFIG env var
+--config uses only the given file(s). If SIDECAR_CONFIG_DIR is not set, it is the
+.sidecar directory of the current directory or its nearest parent (up to the git
+root) that has a .sidecar/bridge.yaml.
 `)
 }
@@ -24,7 +24,7 @@ func TestDetermineWorkdir(t *testing.T) {
 			name: "CWD /workspaces/project with paths mapping translates to /app/project",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Paths: map[string]string{
 					"/workspaces": "/app",
 				},
@@ -36,7 +36,7 @@ func TestDetermineWorkdir(t *testing.T) {
 			name: "CWD with nested path mapping",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Paths: map[string]string{
 					"/workspaces":         "/app",
 					"/workspaces/project": "/project",
@@ -49,7 +49,7 @@ func TestDetermineWorkdir(t *testing.T) {
 			name: "CWD /other/path with no matching paths uses static workdir",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Workdir:   "/default/workdir",
 				Paths: map[string]string{
 					"/workspaces": "/app",
@@ -62,7 +62,7 @@ func TestDetermineWorkdir(t *testing.T) {
 			name: "CWD /other/path with no matching paths and no workdir uses CWD",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Paths: map[string]string{
 					"/workspaces": "/app",
 				},
@@ -74,7 +74,7 @@ func TestDetermineWorkdir(t *testing.T) {
 			name: "No paths mapping uses static workdir if set",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Workdir:   "/default/workdir",
 			},
 			cwd:      "/some/path",
@@ -84,7 +84,7 @@ func TestDetermineWorkdir(t *testing.T) {
 			name: "No paths mapping and no workdir uses CWD",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 			},
 			cwd:      "/some/path",
 			expected: "/some/path",
@@ -93,7 +93,7 @@ func TestDetermineWorkdir(t *testing.T) {
 			name: "Identity mapping uses translated path (same as CWD) not static workdir",
 			cmd: Command{
 				Container: "test",
-				Exec:      "go",
+				Exec:      []string{"go"},
 				Workdir:   "/default/workdir",
 				Paths: map[string]string{
 					"/workspace": "/workspace",
@@ -152,8 +152,8 @@ func TestInitWrappers(t *testing.T) {
 			config: &Config{
 				Version: "1",
 				Commands: map[string]Command{
-					"go":  {Container: "golang", Exec: "go"},
-					"npm": {Container: "node", Exec: "npm"},
+					"go":  {Container: "golang", Exec: []string{"go"}},
+					"npm": {Container: "node", Exec: []string{"npm"}},
 				},
 			},
 			expectedCreated: 2,
@@ -164,7 +164,7 @@ func TestInitWrappers(t *testing.T) {
 			config: &Config{
 				Version: "1",
 				Commands: map[string]Command{
-					"go": {Container: "golang", Exec: "go"},
+					"go": {Container: "golang", Exec: []string{"go"}},
 				},
 			},
 			setup: func(t *testing.T, dir string) {
@@ -181,7 +181,7 @@ func TestInitWrappers(t *testing.T) {
 			config: &Config{
 				Version: "1",
 				Commands: map[string]Command{
-					"go": {Container: "golang", Exec: "go"},
+					"go": {Container: "golang", Exec: []string{"go"}},
 				},
 			},
 			setup: func(t *testing.T, dir string) {
@@ -198,7 +198,7 @@ func TestInitWrappers(t *testing.T) {
 			config: &Config{
 				Version: "1",
 				Commands: map[string]Command{
-					"dispatcher": {Container: "test", Exec: "dispatcher"}, // edge case
+					"dispatcher": {Container: "test", Exec: []string{"dispatcher"}}, // edge case
 				},
 			},
 			expectedCreated: 0,
@@ -209,8 +209,8 @@ func TestInitWrappers(t *testing.T) {
 			config: &Config{
 				Version: "1",
 				Commands: map[string]Command{
-					"go":  {Container: "golang", Exec: "go"},
-					"npm": {Container: "node", Exec: "npm"},
+					"go":  {Container: "golang", Exec: []string{"go"}},
+					"npm": {Container: "node", Exec: []string{"npm"}},
 				},
 			},
 			expectedCreated: 2,
@@ -221,7 +221,7 @@ func TestInitWrappers(t *testing.T) {
 			config: &Config{
 				Version: "1",
 				Commands: map[string]Command{
-					"go": {Container: "golang", Exec: "go"},
+					"go": {Container: "golang", Exec: []string{"go"}},
 				},
 			},
 			setup: func(t *testing.T, dir string) {
@@ -306,8 +306,8 @@ func TestInitWrappers_Idempotent(t *testing.T) {
 	config := &Config{
 		Version: "1",
 		Commands: map[string]Command{
-			"go":  {Container: "golang", Exec: "go"},
-			"npm": {Container: "node", Exec: "npm"},
+			"go":  {Container: "golang", Exec: []string{"go"}},
+			"npm": {Container: "node", Exec: []string{"npm"}},
 		},
 	}
 
@@ -330,6 +330,29 @@ func TestInitWrappers_Idempotent(t *testing.T) {
 	}
 }
 
+func TestAfterSeparator(t *testing.T) {
+	tests := []struct {
+		name string
+		argv []string
+		rest int
+		want bool
+	}{
+		{"wrapper invocation", []string{"--", "config", "--global"}, 2, true},
+		{"flags before separator", []string{"--profile", "ci", "--", "list"}, 1, true},
+		{"subcommand", []string{"config", "validate"}, 2, false},
+		{"separator as command argument", []string{"npm", "--", "x"}, 3, false},
+		{"no arguments", []string{"--json"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := afterSeparator(tt.argv, tt.rest); got != tt.want {
+				t.Errorf("afterSeparator(%q, %d) = %v, want %v", tt.argv, tt.rest, got, tt.want)
+			}
+		})
+	}
+}
+
 // contains checks if s contains substr
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsAt(s, substr))
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// currentVersion is the config schema version used internally. Files
+// declaring an older version are upgraded as they are read.
+//
+// Version 2 differs from version 1 in that:
+//   - containers are always mappings (version 1 also accepts a bare name)
+//   - exec is an argument list (version 1 takes a string)
+const currentVersion = "2"
+
+// fileVersion returns the version a config file declares, or "" if none.
+func fileVersion(root *yaml.Node) string {
+	if idx := mappingIndex(root, "version"); idx >= 0 {
+		return root.Content[idx+1].Value
+	}
+	return ""
+}
+
+// upgradeFile upgrades a config file read from disk to the current version.
+// A file without a version key takes the version of the file that included
+// it; version 1 is assumed otherwise, which leaves version 2 content as is.
+// Returns the file's effective version.
+func upgradeFile(root *yaml.Node, inherited string) string {
+	version := fileVersion(root)
+	if version == "" {
+		version = inherited
+	}
+	if version == "" || version == "1" {
+		upgradeV1(root)
+	}
+	return version
+}
+
+// upgradeEdit records a value replaced by upgradeV1.
+type upgradeEdit struct {
+	key *yaml.Node // Key node of the replaced value
+	old *yaml.Node // Original scalar
+	new *yaml.Node // Replacement value
+}
+
+// upgradeV1 converts a version 1 config tree to version 2 in place:
+// container names become mappings with a name key and exec strings become
+// argument lists, split on whitespace. A version key is set to "2".
+// Comments stay with the values they were on. Returns the replaced values.
+func upgradeV1(root *yaml.Node) []upgradeEdit {
+	var edits []upgradeEdit
+
+	replace := func(key *yaml.Node, value **yaml.Node, replacement *yaml.Node) {
+		edits = append(edits, upgradeEdit{key: key, old: *value, new: replacement})
+		*value = replacement
+	}
+
+	if idx := mappingIndex(root, "version"); idx >= 0 {
+		if v := root.Content[idx+1]; v.Value != currentVersion {
+			replace(root.Content[idx], &root.Content[idx+1], &yaml.Node{
+				Kind: yaml.ScalarNode, Tag: "!!str", Value: currentVersion, Style: yaml.DoubleQuotedStyle,
+				Line: v.Line, Column: v.Column, HeadComment: v.HeadComment, LineComment: v.LineComment, FootComment: v.FootComment,
+			})
+		}
+	}
+
+	upgradeContainers := func(section *yaml.Node) {
+		forEachEntry(section, func(key *yaml.Node, value **yaml.Node) {
+			if (*value).Kind != yaml.ScalarNode || isNull(*value) {
+				return
+			}
+			name := *value
+			replace(key, value, &yaml.Node{
+				Kind: yaml.MappingNode, Tag: "!!map", Line: name.Line, Column: name.Column,
+				Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: "name", Line: name.Line, Column: name.Column},
+					name,
+				},
+			})
+		})
+	}
+
+	upgradeExec := func(mapping *yaml.Node) {
+		idx := mappingIndex(mapping, "exec")
+		if idx < 0 {
+			return
+		}
+		exec := mapping.Content[idx+1]
+		if exec.Kind != yaml.ScalarNode || isNull(exec) {
+			return
+		}
+		argv := &yaml.Node{
+			Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle,
+			Line: exec.Line, Column: exec.Column, LineComment: exec.LineComment,
+		}
+		for _, arg := range strings.Fields(exec.Value) {
+			argv.Content = append(argv.Content, &yaml.Node{
+				Kind: yaml.ScalarNode, Tag: "!!str", Value: arg, Line: exec.Line, Column: exec.Column,
+			})
+		}
+		replace(mapping.Content[idx], &mapping.Content[idx+1], argv)
+	}
+
+	upgradeCommands := func(section *yaml.Node) {
+		forEachEntry(section, func(_ *yaml.Node, value **yaml.Node) {
+			cmd := *value
+			if cmd.Kind != yaml.MappingNode {
+				return
+			}
+			upgradeExec(cmd)
+			if routes := mappingValue(cmd, "routes"); routes != nil && routes.Kind == yaml.SequenceNode {
+				for _, route := range routes.Content {
+					if route.Kind == yaml.MappingNode {
+						upgradeExec(route)
+					}
+				}
+			}
+		})
+	}
+
+	upgradeContainers(mappingValue(root, "containers"))
+	upgradeCommands(mappingValue(root, "commands"))
+	forEachEntry(mappingValue(root, "scopes"), func(_ *yaml.Node, scope **yaml.Node) {
+		upgradeCommands(mappingValue(*scope, "commands"))
+	})
+	forEachEntry(mappingValue(root, "profiles"), func(_ *yaml.Node, profile **yaml.Node) {
+		upgradeContainers(mappingValue(*profile, "containers"))
+		upgradeCommands(mappingValue(*profile, "commands"))
+	})
+
+	return edits
+}
+
+// mappingValue returns the value of key in a mapping, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	if idx := mappingIndex(mapping, key); idx >= 0 {
+		return mapping.Content[idx+1]
+	}
+	return nil
+}
+
+// forEachEntry calls fn for each entry of a mapping with its key and a
+// pointer to its value slot, so the value can be replaced. A nil or
+// non-mapping node has no entries.
+func forEachEntry(mapping *yaml.Node, fn func(key *yaml.Node, value **yaml.Node)) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		fn(mapping.Content[i], &mapping.Content[i+1])
+	}
+}
+
+// configMigrateCommand implements `bridge config migrate`.
+func configMigrateCommand(args []string, configPath string) int {
+	fs := flag.NewFlagSet("config migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Show the changes without writing them")
+	yes := fs.Bool("yes", false, "Write the changes without asking")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	path := fs.Arg(0)
+	if path == "" {
+		path = configPath
+	}
+	if path == "" {
+		path = getDefaultConfigPath()
+	}
+
+	original, migrated, err := migrateFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if bytes.Equal(original, migrated) {
+		fmt.Printf("%s is already version %s\n", path, currentVersion)
+		return 0
+	}
+
+	writeDiff(os.Stdout, path, "", "(version "+currentVersion+")", string(original), string(migrated))
+	if *dryRun {
+		return 0
+	}
+	if !*yes {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Fprintln(os.Stderr, "Not writing changes; run with --yes to apply them")
+			return 1
+		}
+		if !confirm(os.Stdin, os.Stdout, fmt.Sprintf("Write changes to %s?", path)) {
+			return 1
+		}
+	}
+
+	if err := writeFileAtomic(path, migrated); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Migrated %s to version %s\n", path, currentVersion)
+	return 0
+}
+
+// migrateFile returns the contents of a config file and the contents after
+// upgrading it to the current version. ${VAR} references are left
+// unexpanded. Changed values are edited in place in the original text, so
+// comments, blank lines and key order are kept; if that is not possible
+// (for example with flow-style mappings) the upgraded tree is re-encoded,
+// which keeps comments and order but not blank lines. A file already at the
+// current version is returned unchanged.
+func migrateFile(path string) (original, migrated []byte, err error) {
+	original, err = os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(original, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid YAML in %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("invalid YAML in %s: top level must be a mapping", path)
+	}
+	root := doc.Content[0]
+
+	switch version := fileVersion(root); version {
+	case currentVersion:
+		return original, original, nil
+	case "", "1":
+	default:
+		return nil, nil, fmt.Errorf("%s: unsupported config version '%s'", path, version)
+	}
+	edits := upgradeV1(root)
+	if len(edits) == 0 {
+		return original, original, nil
+	}
+
+	if patched, ok := patchUpgrade(original, edits); ok && sameYAML(patched, &doc) {
+		return original, patched, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, nil, err
+	}
+	return original, buf.Bytes(), nil
+}
+
+// patchUpgrade applies upgrade edits to the original text line by line.
+// It reports false if an edited value cannot be located exactly, such as a
+// multi-line scalar or a value inside a flow collection.
+func patchUpgrade(original []byte, edits []upgradeEdit) ([]byte, bool) {
+	lines := strings.Split(string(original), "\n")
+
+	// Apply from the bottom up so inserted lines do not shift later edits
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].old.Line > edits[j].old.Line })
+	for i := 1; i < len(edits); i++ {
+		if edits[i].old.Line == edits[i-1].old.Line {
+			return nil, false
+		}
+	}
+
+	for _, e := range edits {
+		idx := e.old.Line - 1
+		if idx >= len(lines) || e.old.Column-1 > len(lines[idx]) {
+			return nil, false
+		}
+		line := lines[idx]
+		prefix, rest := line[:e.old.Column-1], line[e.old.Column-1:]
+
+		// Split the value's source text from its trailing comment
+		comment := ""
+		if e.old.LineComment != "" {
+			c := strings.LastIndex(rest, e.old.LineComment)
+			if c < 0 {
+				return nil, false
+			}
+			rest, comment = rest[:c], rest[c:]
+		}
+		source := strings.TrimRight(rest, " \t")
+		var value string
+		if err := yaml.Unmarshal([]byte(source), &value); err != nil || value != e.old.Value {
+			return nil, false
+		}
+		if comment != "" {
+			comment = " " + comment
+		}
+
+		switch e.new.Kind {
+		case yaml.MappingNode:
+			// key: name  ->  key:\n  name: name
+			if e.key.Line != e.old.Line {
+				return nil, false
+			}
+			indent := strings.Repeat(" ", e.key.Column-1+2)
+			lines[idx] = strings.TrimRight(prefix, " \t")
+			lines = append(lines[:idx+1], append([]string{indent + "name: " + source + comment}, lines[idx+1:]...)...)
+		default:
+			text, err := yaml.Marshal(&yaml.Node{Kind: e.new.Kind, Tag: e.new.Tag, Style: e.new.Style, Value: e.new.Value, Content: e.new.Content})
+			if err != nil {
+				return nil, false
+			}
+			lines[idx] = prefix + strings.TrimSpace(string(text)) + comment
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n")), true
+}
+
+// sameYAML reports whether data parses to the same values as doc.
+func sameYAML(data []byte, doc *yaml.Node) bool {
+	var got, want interface{}
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		return false
+	}
+	if err := doc.Decode(&want); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+// confirm asks a yes/no question and reports whether the answer was yes.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// writeFileAtomic replaces the file at path with data by writing a
+// temporary file in the same directory and renaming it over the original.
+// The original file's permissions are kept.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeDiff writes a line diff between before and after in unified format,
+// with three lines of context around each change. The header names path on
+// both sides, each followed by its label if any.
+func writeDiff(w io.Writer, path, beforeLabel, afterLabel, before, after string) {
+	a := strings.SplitAfter(before, "\n")
+	b := strings.SplitAfter(after, "\n")
+	if len(a) > 0 && a[len(a)-1] == "" {
+		a = a[:len(a)-1]
+	}
+	if len(b) > 0 && b[len(b)-1] == "" {
+		b = b[:len(b)-1]
+	}
+
+	// Longest common subsequence table, filled from the end
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type diffLine struct {
+		op   byte // ' ', '-' or '+'
+		text string
+		i, j int // Line indexes in a and b before this line
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i, j})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", strings.TrimSpace(path+" "+beforeLabel), strings.TrimSpace(path+" "+afterLabel))
+
+	const context = 3
+	for start := 0; start < len(lines); {
+		// Find the next change
+		for start < len(lines) && lines[start].op == ' ' {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+		first := max(start-context, 0)
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		end, lastChange := start, start
+		for end < len(lines) && end-lastChange <= 2*context {
+			if lines[end].op != ' ' {
+				lastChange = end
+			}
+			end++
+		}
+		last := min(lastChange+context+1, len(lines))
+
+		var oldCount, newCount int
+		for _, l := range lines[first:last] {
+			if l.op != '+' {
+				oldCount++
+			}
+			if l.op != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", lines[first].i+1, oldCount, lines[first].j+1, newCount)
+		for _, l := range lines[first:last] {
+			fmt.Fprintf(w, "%c%s", l.op, strings.TrimSuffix(l.text, "\n"))
+			fmt.Fprintln(w)
+		}
+		start = last
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const v1Config = `# Project config
+version: "1"
+
+containers:
+  app: myproject-app-1 # main container
+  php:
+    name: myproject-php-1
+    workdir: /var/www/html
+
+commands:
+  # Laravel
+  artisan:
+    container: php
+    exec: php artisan
+    routes:
+      - match:
+          prefix: [test]
+        exec: php artisan test --parallel
+  npm:
+    container: app
+    exec: npm
+`
+
+func TestLoadConfigUpgradesV1(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, v1Config), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Version != "2" {
+		t.Errorf("Version = %q, want 2", config.Version)
+	}
+	if config.ResolveContainer("app") != "myproject-app-1" {
+		t.Errorf("app resolved to %q", config.ResolveContainer("app"))
+	}
+	if got := config.Commands["artisan"].Exec; !reflect.DeepEqual(got, []string{"php", "artisan"}) {
+		t.Errorf("artisan exec = %q", got)
+	}
+	if got := config.Commands["artisan"].Routes[0].Exec; !reflect.DeepEqual(got, []string{"php", "artisan", "test", "--parallel"}) {
+		t.Errorf("route exec = %q", got)
+	}
+}
+
+func TestLoadConfigV2RequiresArgv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "exec list",
+			content: `version: "2"
+containers:
+  app:
+    name: myproject-app-1
+commands:
+  npm:
+    container: app
+    exec: [npm, --prefix, /app]
+`,
+		},
+		{
+			name: "exec string",
+			content: `version: "2"
+commands:
+  npm:
+    container: app
+    exec: npm
+`,
+			wantErr: "bridge.yaml:5:5: error: commands.npm.exec: expected a list, got 'npm'",
+		},
+		{
+			name: "container name only",
+			content: `version: "2"
+containers:
+  app: myproject-app-1
+commands:
+  npm:
+    container: app
+    exec: [npm]
+`,
+			wantErr: "containers.app: expected a mapping, got 'myproject-app-1'",
+		},
+		{
+			name: "unsupported version",
+			content: `version: "3"
+commands:
+  npm:
+    container: app
+    exec: [npm]
+`,
+			wantErr: "unsupported config version '3', expected '1' or '2'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.content), "")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFragmentsInheritVersion(t *testing.T) {
+	fragment := "commands:\n  npx:\n    container: app\n    exec: npx\n"
+
+	t.Run("version 1 base", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"bridge.yaml":         "version: \"1\"\ncommands:\n  npm:\n    container: app\n    exec: npm\n",
+			"bridge.d/extra.yaml": fragment,
+		})
+		config, err := LoadConfig(filepath.Join(dir, "bridge.yaml"), "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := config.Commands["npx"].Exec; !reflect.DeepEqual(got, []string{"npx"}) {
+			t.Errorf("npx exec = %q", got)
+		}
+	})
+
+	t.Run("version 2 base", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"bridge.yaml":         "version: \"2\"\ncommands:\n  npm:\n    container: app\n    exec: [npm]\n",
+			"bridge.d/extra.yaml": fragment,
+		})
+		_, err := LoadConfig(filepath.Join(dir, "bridge.yaml"), "")
+		if err == nil || !contains(err.Error(), "extra.yaml:4:5: error: commands.npx.exec: expected a list") {
+			t.Errorf("expected exec list error in fragment, got %v", err)
+		}
+	})
+}
+
+func TestMigrateFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "edits in place",
+			content: v1Config,
+			want: `# Project config
+version: "2"
+
+containers:
+  app:
+    name: myproject-app-1 # main container
+  php:
+    name: myproject-php-1
+    workdir: /var/www/html
+
+commands:
+  # Laravel
+  artisan:
+    container: php
+    exec: [php, artisan]
+    routes:
+      - match:
+          prefix: [test]
+        exec: [php, artisan, test, --parallel]
+  npm:
+    container: app
+    exec: [npm]
+`,
+		},
+		{
+			name: "scopes and profiles",
+			content: `version: 1
+scopes:
+  /workspace/api:
+    commands:
+      go:
+        container: api-go
+        exec: go
+profiles:
+  ci:
+    containers:
+      app: ci-app
+    commands:
+      npm:
+        container: app
+        exec: "npm"
+`,
+			want: `version: "2"
+scopes:
+  /workspace/api:
+    commands:
+      go:
+        container: api-go
+        exec: [go]
+profiles:
+  ci:
+    containers:
+      app:
+        name: ci-app
+    commands:
+      npm:
+        container: app
+        exec: [npm]
+`,
+		},
+		{
+			name: "flow mapping is re-encoded",
+			content: `version: "1"
+containers: {app: myproject-app-1}
+commands:
+  npm: {container: app, exec: npm}
+`,
+			want: `version: "2"
+containers: {app: {name: myproject-app-1}}
+commands:
+  npm: {container: app, exec: [npm]}
+`,
+		},
+		{
+			name:    "already version 2",
+			content: "version: \"2\"\ncommands:\n  npm:\n    container: app\n    exec: [npm]\n",
+			want:    "version: \"2\"\ncommands:\n  npm:\n    container: app\n    exec: [npm]\n",
+		},
+		{
+			name:    "unsupported version",
+			content: "version: \"7\"\n",
+			wantErr: "unsupported config version '7'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.content)
+			original, migrated, err := migrateFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(original) != tt.content {
+				t.Errorf("original = %q", original)
+			}
+			if string(migrated) != tt.want {
+				t.Errorf("migrated =\n%s\nwant\n%s", migrated, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigratedConfigLoadsTheSame(t *testing.T) {
+	path := writeConfig(t, v1Config)
+	before, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatalf("load v1: %v", err)
+	}
+
+	_, migrated, err := migrateFile(path)
+	if err != nil {
+		t.Fatalf("migrateFile: %v", err)
+	}
+	after, err := LoadConfig(writeConfig(t, string(migrated)), "")
+	if err != nil {
+		t.Fatalf("load migrated: %v", err)
+	}
+
+	before.Sources, after.Sources = nil, nil
+	before.Origins, after.Origins = nil, nil
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("migrated config differs:\nbefore %+v\nafter  %+v", before, after)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+
+	var buf bytes.Buffer
+	writeDiff(&buf, "bridge.yaml", "", "(version 2)", before, after)
+
+	want := `--- bridge.yaml
++++ bridge.yaml (version 2)
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if buf.String() != want {
+		t.Errorf("diff =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bridge.yaml")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new")); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Errorf("content = %q, %v", data, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temporary file left behind: %v", entries)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for input, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		var out bytes.Buffer
+		if got := confirm(bytes.NewBufferString(input), &out, "Write?"); got != want {
+			t.Errorf("confirm(%q) = %v, want %v", input, got, want)
+		}
+		if out.String() != "Write? [y/N] " {
+			t.Errorf("prompt = %q", out.String())
+		}
+	}
+}
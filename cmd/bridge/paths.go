@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// autoPaths is the paths value that asks for mappings inferred from mounts.
+const autoPaths = "auto"
+
+// autoPathsKey marks a PathMap written as "auto". The empty prefix never
+// wins in TranslatePathWithMatch, so the marker is inert until resolved.
+const autoPathsKey = ""
+
+// PathMap maps path prefixes as the bridge sees them to paths in the
+// container. Written as "auto", the mappings are inferred at run time from
+// the bind mounts the bridge's container shares with the target container
+// (see resolvePaths). Entries merged over "auto" (by extends) win over
+// inferred ones.
+type PathMap map[string]string
+
+// UnmarshalYAML accepts "auto" in place of a mapping.
+func (p *PathMap) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && node.Value == autoPaths {
+		*p = PathMap{autoPathsKey: autoPaths}
+		return nil
+	}
+	var m map[string]string
+	if err := node.Decode(&m); err != nil {
+		return err
+	}
+	*p = m
+	return nil
+}
+
+// Auto reports whether the mappings are to be inferred from mounts.
+func (p PathMap) Auto() bool {
+	_, ok := p[autoPathsKey]
+	return ok
+}
+
+// explicit returns the mappings without the auto marker.
+func (p PathMap) explicit() PathMap {
+	if !p.Auto() {
+		return p
+	}
+	m := make(PathMap, len(p)-1)
+	for k, v := range p {
+		if k != autoPathsKey {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// resolvePaths replaces "auto" with the mappings inferred for container,
+// keeping explicit entries over inferred ones. If the mounts cannot be
+// inspected, a warning is printed and only the explicit entries are used.
+// Without cached, the mounts are always inspected and the cache, which the
+// agent can write, is neither read nor written.
+func resolvePaths(paths PathMap, container string, cached bool) PathMap {
+	if !paths.Auto() {
+		return paths
+	}
+	explicit := paths.explicit()
+
+	inferred, err := inferPaths(container, cached)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: paths: auto for container '%s': %s\n", container, err)
+		return explicit
+	}
+	return PathMap(mergeStringMaps(inferred, explicit))
+}
+
+// inferPaths maps the bridge container's bind mounts to the target
+// container's mounts of the same host directories. Results are cached per
+// pair of container IDs, so the bridge's own container is only inspected
+// once per target container.
+func inferPaths(container string, cached bool) (map[string]string, error) {
+	targets, err := inspectContainers(container)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("container not found")
+	}
+	target := targets[0]
+
+	hostname, _ := os.Hostname()
+	cache := make(map[string]map[string]string)
+	if cached {
+		cache = readPathCache()
+	}
+	key := hostname + "/" + target.ID
+	if paths, ok := cache[key]; ok {
+		return paths, nil
+	}
+
+	self, err := selfContainer()
+	if err != nil {
+		return nil, fmt.Errorf("cannot inspect the bridge's own container: %w", err)
+	}
+	paths := mountPaths(self.Mounts, target.Mounts)
+	if !cached {
+		return paths, nil
+	}
+
+	// Entries for other bridge containers are stale: drop them
+	for k := range cache {
+		if !strings.HasPrefix(k, hostname+"/") {
+			delete(cache, k)
+		}
+	}
+	cache[key] = paths
+	writePathCache(cache)
+	return paths, nil
+}
+
+// pathCachePath returns the file caching inferred path mappings.
+func pathCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "bridge", "paths.json")
+}
+
+// readPathCache returns the cached mappings keyed by "<bridge>/<target ID>".
+// A missing or unreadable cache is empty.
+func readPathCache() map[string]map[string]string {
+	cache := make(map[string]map[string]string)
+	if path := pathCachePath(); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			_ = json.Unmarshal(data, &cache)
+		}
+	}
+	return cache
+}
+
+// writePathCache stores the cache. Failures are ignored; the mappings are
+// inferred again next time.
+func writePathCache(cache map[string]map[string]string) {
+	path := pathCachePath()
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	_ = writeFileAtomic(path, data)
+}
+
+// mountPaths maps the bridge's view of each host directory the target
+// bind-mounts to the path inside the target. A target mount is matched with
+// the bridge mount of the same host directory or the closest parent, so
+// the bridge's /workspace (the project) maps to a target's /var/www/html
+// (the project) or /app (the project's frontend directory). Mounts at the
+// same path in both are kept, so that a nested mapping does not become the
+// longest prefix for paths outside it.
+func mountPaths(bridge, target []containerMount) map[string]string {
+	paths := make(map[string]string)
+	for _, m := range target {
+		if m.Type != "bind" {
+			continue
+		}
+		best := -1
+		var rel string
+		for i, b := range bridge {
+			if b.Type != "bind" {
+				continue
+			}
+			r, err := filepath.Rel(b.Source, m.Source)
+			if err != nil || r == ".." || strings.HasPrefix(r, "../") {
+				continue
+			}
+			if best < 0 || len(b.Source) > len(bridge[best].Source) {
+				best, rel = i, r
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		paths[filepath.Join(bridge[best].Destination, rel)] = filepath.Clean(m.Destination)
+	}
+	return paths
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMountPaths(t *testing.T) {
+	bridge := []containerMount{
+		{Type: "bind", Source: "/home/dev/project", Destination: "/workspace"},
+		{Type: "bind", Source: "/home/dev/shared", Destination: "/shared"},
+		{Type: "volume", Source: "cache", Destination: "/cache"},
+	}
+
+	tests := []struct {
+		name   string
+		target []containerMount
+		want   map[string]string
+	}{
+		{
+			name:   "same directory",
+			target: []containerMount{{Type: "bind", Source: "/home/dev/project", Destination: "/var/www/html"}},
+			want:   map[string]string{"/workspace": "/var/www/html"},
+		},
+		{
+			name:   "subdirectory",
+			target: []containerMount{{Type: "bind", Source: "/home/dev/project/frontend", Destination: "/app"}},
+			want:   map[string]string{"/workspace/frontend": "/app"},
+		},
+		{
+			name:   "same path in both",
+			target: []containerMount{{Type: "bind", Source: "/home/dev/shared", Destination: "/shared"}},
+			want:   map[string]string{"/shared": "/shared"},
+		},
+		{
+			name: "same path with a nested mount",
+			target: []containerMount{
+				{Type: "bind", Source: "/home/dev/project", Destination: "/workspace"},
+				{Type: "bind", Source: "/home/dev/project/frontend", Destination: "/app"},
+			},
+			want: map[string]string{"/workspace": "/workspace", "/workspace/frontend": "/app"},
+		},
+		{
+			name: "unshared and named volumes",
+			target: []containerMount{
+				{Type: "bind", Source: "/home/dev/other", Destination: "/other"},
+				{Type: "volume", Source: "cache", Destination: "/data"},
+				{Type: "bind", Source: "/home/dev/project-old", Destination: "/old"},
+			},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mountPaths(bridge, tt.target); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mountPaths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigPathsAuto(t *testing.T) {
+	path := writeConfig(t, `version: "2"
+containers:
+  php:
+    name: myproject-php-1
+    paths: auto
+commands:
+  php:
+    container: php
+    exec: [php]
+  artisan:
+    extends: php
+    exec: [php, artisan]
+    paths:
+      /workspace/storage: /tmp/storage
+`)
+
+	var buf bytes.Buffer
+	ok, err := validateConfig(&buf, path)
+	if err != nil || !ok {
+		t.Fatalf("validateConfig = %v, %v: %s", ok, err, buf.String())
+	}
+	if !contains(buf.String(), "ok") {
+		t.Errorf("expected no warnings, got %q", buf.String())
+	}
+
+	config, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd := config.ApplyContainerDefaults(config.Commands["php"]); !cmd.Paths.Auto() {
+		t.Errorf("php paths = %v, want auto", cmd.Paths)
+	}
+
+	_, err = LoadConfig(writeConfig(t, "version: \"2\"\ncommands:\n  php:\n    container: php\n    exec: [php]\n    paths: automatic\n"), "")
+	if err == nil || !contains(err.Error(), "commands.php.paths: expected a mapping, got 'automatic'") {
+		t.Errorf("expected error for bad paths value, got %v", err)
+	}
+}
+
+func TestPathMapTranslateIgnoresMarker(t *testing.T) {
+	cmd := Command{Paths: PathMap{autoPathsKey: autoPaths, "/workspace": "/app"}}
+	if got := cmd.TranslatePath("/workspace/src"); got != "/app/src" {
+		t.Errorf("TranslatePath = %q", got)
+	}
+	if got, matched := cmd.TranslatePathWithMatch("/etc/hosts"); matched || got != "/etc/hosts" {
+		t.Errorf("TranslatePathWithMatch = %q, %v", got, matched)
+	}
+}
+
+func TestResolvePaths(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("no hostname: %v", err)
+	}
+
+	self := `[{"Id": "self1", "Name": "/myproject-claude-1", "Config": {}, "Mounts": [
+	  {"Type": "bind", "Source": "/home/dev/project", "Destination": "/workspace"}]}]`
+	target := `[{"Id": "php1", "Name": "/myproject-php-1", "Config": {}, "Mounts": [
+	  {"Type": "bind", "Source": "/home/dev/project", "Destination": "/var/www/html"},
+	  {"Type": "bind", "Source": "/home/dev/project/storage", "Destination": "/srv/storage"}]}]`
+	calls := fakeDocker(t, map[string]string{
+		"inspect --type container " + hostname:     self,
+		"inspect --type container myproject-php-1": target,
+	})
+
+	paths := PathMap{autoPathsKey: autoPaths, "/workspace/storage": "/data"}
+	want := PathMap{"/workspace": "/var/www/html", "/workspace/storage": "/data"}
+	if got := resolvePaths(paths, "myproject-php-1", true); !reflect.DeepEqual(got, want) {
+		t.Errorf("resolvePaths = %v, want %v", got, want)
+	}
+	if len(*calls) != 2 {
+		t.Errorf("calls = %q, want target and self inspected", *calls)
+	}
+
+	// The second run uses the cache for the same container ID
+	*calls = nil
+	if got := resolvePaths(paths, "myproject-php-1", true); !reflect.DeepEqual(got, want) {
+		t.Errorf("cached resolvePaths = %v, want %v", got, want)
+	}
+	if len(*calls) != 1 {
+		t.Errorf("calls = %q, want only the target inspected", *calls)
+	}
+
+	// Without the cache a tampered entry is ignored and left as it is
+	tampered := map[string]map[string]string{hostname + "/php1": {"/": "/"}}
+	writePathCache(tampered)
+	*calls = nil
+	if got := resolvePaths(paths, "myproject-php-1", false); !reflect.DeepEqual(got, want) {
+		t.Errorf("uncached resolvePaths = %v, want %v", got, want)
+	}
+	if len(*calls) != 2 {
+		t.Errorf("calls = %q, want target and self inspected", *calls)
+	}
+	if cache := readPathCache(); !reflect.DeepEqual(cache, tampered) {
+		t.Errorf("cache = %v, want it unchanged", cache)
+	}
+
+	// Explicit mappings are used unchanged
+	explicit := PathMap{"/workspace": "/app"}
+	if got := resolvePaths(explicit, "myproject-php-1", true); !reflect.DeepEqual(got, explicit) {
+		t.Errorf("resolvePaths(explicit) = %v", got)
+	}
+
+	// Unknown containers fall back to the explicit entries
+	if got := resolvePaths(paths, "missing", true); !reflect.DeepEqual(got, PathMap{"/workspace/storage": "/data"}) {
+		t.Errorf("resolvePaths(missing) = %v", got)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// PresetParams adapts a built-in preset to the project.
+type PresetParams struct {
+	Container string `yaml:"container"`
+	AppPath   string `yaml:"app_path"`
+}
+
+// preset is a curated command set for a common stack.
+type preset struct {
+	container string // Default logical container
+	tool      string // Tool that identifies a container for the preset
+	commands  []presetCommand
+}
+
+// presetCommand is a command a preset defines.
+type presetCommand struct {
+	name string
+	exec []string
+}
+
+// presets are the built-in command sets usable under the presets key.
+var presets = map[string]preset{
+	"laravel": {container: "php", tool: "php", commands: []presetCommand{
+		{"php", []string{"php"}},
+		{"composer", []string{"composer"}},
+		{"artisan", []string{"php", "artisan"}},
+		{"phpunit", []string{"vendor/bin/phpunit"}},
+		{"pest", []string{"vendor/bin/pest"}},
+	}},
+	"symfony": {container: "php", tool: "php", commands: []presetCommand{
+		{"php", []string{"php"}},
+		{"composer", []string{"composer"}},
+		{"console", []string{"php", "bin/console"}},
+		{"phpunit", []string{"vendor/bin/phpunit"}},
+	}},
+	"node": {container: "node", tool: "node", commands: []presetCommand{
+		{"node", []string{"node"}},
+		{"npm", []string{"npm"}},
+		{"npx", []string{"npx"}},
+		{"pnpm", []string{"pnpm"}},
+		{"yarn", []string{"yarn"}},
+	}},
+	"go": {container: "go", tool: "go", commands: []presetCommand{
+		{"go", []string{"go"}},
+		{"gofmt", []string{"gofmt"}},
+		{"golangci-lint", []string{"golangci-lint"}},
+	}},
+	"python": {container: "python", tool: "python", commands: []presetCommand{
+		{"python", []string{"python"}},
+		{"pip", []string{"pip"}},
+		{"pytest", []string{"pytest"}},
+		{"uv", []string{"uv"}},
+	}},
+	"rails": {container: "rails", tool: "ruby", commands: []presetCommand{
+		{"ruby", []string{"ruby"}},
+		{"bundle", []string{"bundle"}},
+		{"rails", []string{"bin/rails"}},
+		{"rake", []string{"bin/rake"}},
+		{"rspec", []string{"bundle", "exec", "rspec"}},
+	}},
+}
+
+// presetNames returns the built-in preset names, sorted.
+func presetNames() []string {
+	return sortedKeys(presets)
+}
+
+// expandPresets adds the commands of each preset in use. A command the
+// config defines itself is layered over the preset's command as with
+// extends, so it can override single fields; one that extends another
+// command replaces the preset's command entirely. Two presets that define
+// the same command are a problem, as neither would clearly win.
+func (c *Config) expandPresets() []configProblem {
+	var problems []configProblem
+	own := make(map[string]Command, len(c.Commands))
+	for name, cmd := range c.Commands {
+		own[name] = cmd
+	}
+	definedBy := make(map[string]string)
+	for _, name := range sortedKeys(c.Presets) {
+		p, ok := presets[name]
+		if !ok {
+			problems = append(problems, problem([]string{"presets", name},
+				"unknown preset '%s' (expected one of: %s)", name, strings.Join(presetNames(), ", ")))
+			continue
+		}
+		params := c.Presets[name]
+		container := p.container
+		if params.Container != "" {
+			container = params.Container
+		}
+
+		if c.Commands == nil {
+			c.Commands = make(map[string]Command)
+		}
+		for _, pc := range p.commands {
+			if other, ok := definedBy[pc.name]; ok {
+				problems = append(problems, problem([]string{"presets", name},
+					"preset '%s' defines command '%s', which preset '%s' also defines; use one of them", name, pc.name, other))
+				continue
+			}
+			definedBy[pc.name] = name
+
+			cmd := Command{Container: container, Exec: pc.exec, Workdir: params.AppPath}
+			if override, ok := own[pc.name]; ok {
+				if override.Extends != "" {
+					continue
+				}
+				cmd = mergeCommand(cmd, override)
+			}
+			c.Commands[pc.name] = cmd
+		}
+	}
+	return problems
+}
+
+// presetCommandNames returns the names of the commands a preset defines.
+func presetCommandNames(name string) []string {
+	var names []string
+	for _, pc := range presets[name].commands {
+		names = append(names, pc.name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfigPresets(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, `version: "2"
+presets:
+  laravel:
+    container: app
+    app_path: /var/www/html
+  node: {}
+commands:
+  phpunit:
+    env:
+      APP_ENV: testing
+  pest:
+    extends: php
+    exec: [php, vendor/bin/pest, --parallel]
+  npm:
+    container: frontend
+`), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want Command
+	}{
+		{"artisan", Command{Container: "app", Exec: []string{"php", "artisan"}, Workdir: "/var/www/html"}},
+		{"phpunit", Command{Container: "app", Exec: []string{"vendor/bin/phpunit"}, Workdir: "/var/www/html", Env: map[string]string{"APP_ENV": "testing"}}},
+		{"pest", Command{Container: "app", Exec: []string{"php", "vendor/bin/pest", "--parallel"}, Workdir: "/var/www/html"}},
+		{"yarn", Command{Container: "node", Exec: []string{"yarn"}}},
+		{"npm", Command{Container: "frontend", Exec: []string{"npm"}}},
+	}
+	for _, tt := range tests {
+		if got := config.Commands[tt.name]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("command %s = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigUnknownPreset(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, `version: "2"
+presets:
+  laravle: {}
+`), "")
+	want := "bridge.yaml:3:3: error: unknown preset 'laravle' (expected one of: go, laravel, node, python, rails, symfony)"
+	if err == nil || !contains(err.Error(), want) {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
+
+func TestLoadConfigOverlappingPresets(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, `version: "2"
+presets:
+  laravel: {}
+  symfony: {}
+`), "")
+	for _, want := range []string{
+		"bridge.yaml:4:3: error: preset 'symfony' defines command 'composer', which preset 'laravel' also defines",
+		"preset 'symfony' defines command 'php'",
+		"preset 'symfony' defines command 'phpunit'",
+	} {
+		if err == nil || !contains(err.Error(), want) {
+			t.Errorf("expected %q, got %v", want, err)
+		}
+	}
+}
+
+func TestPresetsDefineDistinctCommands(t *testing.T) {
+	for name, p := range presets {
+		seen := make(map[string]bool)
+		for _, cmd := range p.commands {
+			if seen[cmd.name] {
+				t.Errorf("preset %s defines %s twice", name, cmd.name)
+			}
+			seen[cmd.name] = true
+			if reservedCommands[cmd.name] {
+				t.Errorf("preset %s defines reserved command %s", name, cmd.name)
+			}
+		}
+		if !seen[p.tool] {
+			t.Errorf("preset %s does not define its tool %s", name, p.tool)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Profile overlays containers, commands and defaults onto the base config.
+// Containers are merged per logical name and commands are replaced per
+// command name; entries the profile does not mention are inherited.
+type Profile struct {
+	DefaultContainer string                     `yaml:"default_container"`
+	Containers       map[string]ContainerConfig `yaml:"containers"`
+	Commands         map[string]Command         `yaml:"commands"`
+}
+
+// activeProfileName returns the profile selected by flag, falling back to
+// the BRIDGE_PROFILE environment variable.
+func activeProfileName(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return os.Getenv("BRIDGE_PROFILE")
+}
+
+// profileNames returns the configured profile names in sorted order.
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithProfile returns a copy of the config with the named profile applied.
+// The base config is not modified. An empty name returns an unmodified copy.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	merged := *c
+	if name == "" {
+		return &merged, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		available := "none defined"
+		if len(c.Profiles) > 0 {
+			available = strings.Join(c.profileNames(), ", ")
+		}
+		return nil, fmt.Errorf("unknown profile '%s' (available: %s)", name, available)
+	}
+
+	if profile.DefaultContainer != "" {
+		merged.DefaultContainer = profile.DefaultContainer
+	}
+
+	if len(profile.Containers) > 0 {
+		merged.Containers = make(map[string]ContainerConfig, len(c.Containers)+len(profile.Containers))
+		for k, v := range c.Containers {
+			merged.Containers[k] = v
+		}
+		for k, v := range profile.Containers {
+			merged.Containers[k] = v
+		}
+	}
+
+	if len(profile.Commands) > 0 {
+		merged.Commands = make(map[string]Command, len(c.Commands)+len(profile.Commands))
+		for k, v := range c.Commands {
+			merged.Commands[k] = v
+		}
+		for k, v := range profile.Commands {
+			merged.Commands[k] = v
+		}
+	}
+
+	merged.ActiveProfile = name
+	return &merged, nil
+}
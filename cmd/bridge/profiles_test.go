@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const profilesConfig = `version: "1"
+default_container: app
+containers:
+  app: myproject-app-1
+  node: myproject-node-1
+commands:
+  npm:
+    container: node
+    exec: npm
+  php:
+    container: app
+    exec: php
+profiles:
+  ci:
+    containers:
+      node: ci-node
+    commands:
+      npm:
+        container: node
+        exec: npm
+        env:
+          CI: "1"
+  review:
+    default_container: reviewer
+    commands:
+      phpstan:
+        container: app
+        exec: vendor/bin/phpstan
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bridge.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigProfiles(t *testing.T) {
+	path := writeConfig(t, profilesConfig)
+
+	t.Run("no profile uses base", func(t *testing.T) {
+		t.Setenv("BRIDGE_PROFILE", "")
+		config, err := LoadConfig(path, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.ResolveContainer("node") != "myproject-node-1" {
+			t.Errorf("node resolved to %q", config.ResolveContainer("node"))
+		}
+		if config.ActiveProfile != "" {
+			t.Errorf("ActiveProfile = %q, want empty", config.ActiveProfile)
+		}
+	})
+
+	t.Run("flag selects profile", func(t *testing.T) {
+		t.Setenv("BRIDGE_PROFILE", "review")
+		config, err := LoadConfig(path, "ci")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.ResolveContainer("node") != "ci-node" {
+			t.Errorf("node resolved to %q, want ci-node", config.ResolveContainer("node"))
+		}
+		if config.ResolveContainer("app") != "myproject-app-1" {
+			t.Errorf("app resolved to %q, want inherited myproject-app-1", config.ResolveContainer("app"))
+		}
+		if config.Commands["npm"].Env["CI"] != "1" {
+			t.Errorf("npm not overridden by profile: %+v", config.Commands["npm"])
+		}
+		if _, ok := config.Commands["php"]; !ok {
+			t.Error("php command not inherited from base")
+		}
+	})
+
+	t.Run("env selects profile", func(t *testing.T) {
+		t.Setenv("BRIDGE_PROFILE", "review")
+		config, err := LoadConfig(path, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.DefaultContainer != "reviewer" || config.ActiveProfile != "review" {
+			t.Errorf("default_container = %q, profile = %q", config.DefaultContainer, config.ActiveProfile)
+		}
+		if _, ok := config.Commands["phpstan"]; !ok {
+			t.Error("phpstan command not added by profile")
+		}
+	})
+
+	t.Run("unknown profile lists available", func(t *testing.T) {
+		_, err := LoadConfig(path, "staging")
+		if err == nil || !contains(err.Error(), "unknown profile 'staging' (available: ci, review)") {
+			t.Errorf("expected unknown profile error, got %v", err)
+		}
+	})
+}
+
+func TestWithProfileDoesNotModifyBase(t *testing.T) {
+	config := &Config{
+		Version:    "1",
+		Containers: map[string]ContainerConfig{"node": {Name: "base-node"}},
+		Commands:   map[string]Command{"npm": {Container: "node", Exec: []string{"npm"}}},
+		Profiles: map[string]Profile{
+			"ci": {
+				Containers: map[string]ContainerConfig{"node": {Name: "ci-node"}},
+				Commands:   map[string]Command{"npx": {Container: "node", Exec: []string{"npx"}}},
+			},
+		},
+	}
+
+	if _, err := config.WithProfile("ci"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Containers["node"].Name != "base-node" {
+		t.Errorf("base containers modified: %v", config.Containers)
+	}
+	if _, ok := config.Commands["npx"]; ok {
+		t.Errorf("base commands modified: %v", config.Commands)
+	}
+}
+
+func TestValidateChecksEveryProfile(t *testing.T) {
+	config := &Config{
+		Version:  "1",
+		Commands: map[string]Command{"npm": {Container: "node", Exec: []string{"npm"}}},
+		Profiles: map[string]Profile{
+			"ci":     {},
+			"broken": {Commands: map[string]Command{"npx": {Container: "node"}}},
+		},
+	}
+
+	err := config.Validate()
+	if err == nil || !contains(err.Error(), "profile 'broken': command 'npx': missing required field 'exec'") {
+		t.Errorf("expected profile validation error, got %v", err)
+	}
+}
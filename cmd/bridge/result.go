@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultOutputLimit is how many bytes of each of stdout and stderr a JSON
+// result keeps, unless BRIDGE_OUTPUT_LIMIT says otherwise.
+const defaultOutputLimit = 64 * 1024
+
+// timeoutExitCode is the exit code of a run stopped by BRIDGE_TIMEOUT, as
+// with timeout(1).
+const timeoutExitCode = 124
+
+// Error kinds of a JSON result.
+const (
+	kindConfig            = "config"              // The config failed to load, or the command could not be routed
+	kindDocker            = "docker"              // The Docker CLI could not be run or could not reach the API
+	kindContainerNotFound = "container_not_found" // No container has the resolved name
+	kindContainerStopped  = "container_stopped"   // The container exists but is not running
+	kindExecNotFound      = "exec_not_found"      // The executable is missing in the container
+	kindTimeout           = "timeout"             // The run exceeded BRIDGE_TIMEOUT
+	kindSignal            = "signal"              // The tool was killed by a signal
+	kindToolFailure       = "tool_failure"        // The tool ran and exited non-zero
+)
+
+// runResult is the JSON document `bridge --json` prints for a run.
+type runResult struct {
+	Command         string    `json:"command"`
+	Container       string    `json:"container,omitempty"`
+	Native          string    `json:"native,omitempty"`
+	Exec            []string  `json:"exec,omitempty"`
+	Args            []string  `json:"args"`
+	Workdir         string    `json:"workdir,omitempty"`
+	ExitCode        int       `json:"exit_code"`
+	DurationMs      int64     `json:"duration_ms"`
+	Stdout          string    `json:"stdout"`
+	StdoutTruncated bool      `json:"stdout_truncated,omitempty"`
+	Stderr          string    `json:"stderr"`
+	StderrTruncated bool      `json:"stderr_truncated,omitempty"`
+	Warnings        []string  `json:"warnings,omitempty"`
+	Error           *runError `json:"error,omitempty"`
+}
+
+// runError classifies why a run did not succeed.
+type runError struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// jsonOutput reports whether runs print a JSON result, from --json or
+// BRIDGE_OUTPUT=json.
+func jsonOutput(flagSet bool) bool {
+	return flagSet || strings.ToLower(os.Getenv("BRIDGE_OUTPUT")) == "json"
+}
+
+// fail records the error kind and message, and the exit code to exit with.
+func (r *runResult) fail(kind string, code int, format string, args ...any) {
+	r.Error = &runError{Kind: kind, Message: fmt.Sprintf(format, args...)}
+	r.ExitCode = code
+}
+
+// write prints the result as one JSON document.
+func (r *runResult) write(w io.Writer) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+	}
+}
+
+// configErrorResult is the result of a run whose config failed to load.
+func configErrorResult(args []string, err error) *runResult {
+	r := &runResult{Command: args[0], Args: args[1:]}
+	r.fail(kindConfig, 1, "%s", err)
+	return r
+}
+
+// runCommandJSON routes and runs the command like runCommand, but captures
+// its output and prints a runResult on stdout instead. Stdin is passed
+// through. It returns the same exit code runCommand would.
+func runCommandJSON(config *Config, args []string, w io.Writer) int {
+	start := time.Now()
+	r := &runResult{Command: args[0], Args: args[1:]}
+	defer func() {
+		r.DurationMs = time.Since(start).Milliseconds()
+		r.write(w)
+	}()
+
+	limit, timeout, err := resultSettings()
+	if err != nil {
+		r.fail(kindConfig, 1, "%s", err)
+		return r.ExitCode
+	}
+
+	inv, err := routeCommand(config, args, debugTracer())
+	if err != nil {
+		r.fail(kindConfig, exitCode(err), "%s", err)
+		return r.ExitCode
+	}
+
+	name, argv := inv.Native, inv.Args
+	if inv.Args != nil {
+		r.Args = inv.Args
+	}
+	if inv.Native != "" {
+		r.Native = inv.Native
+	} else {
+		r.Container, r.Exec, r.Workdir = inv.Container, inv.Exec, inv.Workdir
+		inv.TTY = false
+		if timeout > 0 {
+			// Killing the docker client alone would leave the tool running
+			// in the container, so it is stopped there as well when the
+			// container has timeout(1)
+			if hasTimeout(inv, timeout) {
+				seconds := strconv.FormatFloat(timeout.Seconds(), 'f', -1, 64)
+				inv.Exec = append([]string{"timeout", "-s", "KILL", seconds}, inv.Exec...)
+			} else {
+				r.Warnings = append(r.Warnings, fmt.Sprintf("'timeout' cannot be run in %s, so BRIDGE_TIMEOUT stops only the docker client and the tool may keep running in the container", inv.Container))
+			}
+		}
+		name, argv = "docker", inv.dockerArgs()
+	}
+	r.capture(name, argv, limit, timeout)
+	return r.ExitCode
+}
+
+// hasTimeout reports whether timeout(1) can be run in the container of inv.
+// Only a missing executable counts; other failures, such as a stopped
+// container, are left for the run itself to report.
+func hasTimeout(inv *invocation, limit time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), limit)
+	defer cancel()
+	probe := invocation{Container: inv.Container, Exec: []string{"timeout", "1", "true"}, Workdir: inv.Workdir, User: inv.User}
+	err := exec.CommandContext(ctx, "docker", probe.dockerArgs()...).Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return true
+	}
+	return exitErr.ExitCode() != 126 && exitErr.ExitCode() != 127
+}
+
+// resultSettings reads BRIDGE_OUTPUT_LIMIT and BRIDGE_TIMEOUT. A timeout of
+// zero means none.
+func resultSettings() (limit int, timeout time.Duration, err error) {
+	limit = defaultOutputLimit
+	if v := os.Getenv("BRIDGE_OUTPUT_LIMIT"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			return 0, 0, fmt.Errorf("BRIDGE_OUTPUT_LIMIT: '%s' is not a number of bytes", v)
+		}
+	}
+	if v := os.Getenv("BRIDGE_TIMEOUT"); v != "" {
+		if timeout, err = time.ParseDuration(v); err != nil || timeout < 0 {
+			return 0, 0, fmt.Errorf("BRIDGE_TIMEOUT: '%s' is not a duration such as 30s or 5m", v)
+		}
+	}
+	return limit, timeout, nil
+}
+
+// capture runs name with args, keeping the last limit bytes of each output
+// stream, and classifies the outcome.
+func (r *runResult) capture(name string, args []string, limit int, timeout time.Duration) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	stdout, stderr := &tailBuffer{limit: limit}, &tailBuffer{limit: limit}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second // Don't wait on output held open by the tool's children
+	start := time.Now()
+	err := cmd.Run()
+	timedOut := ctx.Err() == context.DeadlineExceeded || (timeout > 0 && err != nil && time.Since(start) >= timeout)
+
+	r.Stdout, r.StdoutTruncated = stdout.String(), stdout.truncated
+	r.Stderr, r.StderrTruncated = stderr.String(), stderr.truncated
+
+	var exitErr *exec.ExitError
+	switch {
+	case timedOut: // Possibly stopped by timeout(1) in the container just before the deadline here
+		r.fail(kindTimeout, timeoutExitCode, "command '%s' timed out after %s", r.Command, timeout)
+	case errors.As(err, &exitErr):
+		r.classifyExit(exitErr)
+	case err != nil:
+		if r.Native != "" {
+			r.fail(kindExecNotFound, 127, "failed to exec '%s': %s", r.Native, err)
+		} else {
+			r.fail(kindDocker, 1, "failed to execute docker: %s", err)
+		}
+	}
+}
+
+// classifyExit sets the error for a run that exited non-zero. Failures of
+// docker exec itself are told apart from the tool's own by their exit code
+// and the daemon's message on stderr.
+func (r *runResult) classifyExit(exitErr *exec.ExitError) {
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		r.fail(kindSignal, 128+int(status.Signal()), "command '%s' was killed by signal %s", r.Command, status.Signal())
+		return
+	}
+
+	code := exitErr.ExitCode()
+	last := lastLine(r.Stderr)
+	lower := strings.ToLower(last)
+	daemon := strings.HasPrefix(lower, "error response from daemon:")
+	if r.Native == "" {
+		switch {
+		case daemon && strings.Contains(lower, "no such container"):
+			r.fail(kindContainerNotFound, code, "container %s not found: %s", r.Container, last)
+			return
+		case daemon && (strings.Contains(lower, "is not running") || strings.Contains(lower, "is paused") || strings.Contains(lower, "is restarting")):
+			r.fail(kindContainerStopped, code, "container %s is not running: %s", r.Container, last)
+			return
+		case (code == 126 || code == 127) && (strings.Contains(lower, "oci runtime exec failed") || strings.Contains(lower, "executable file not found")):
+			r.fail(kindExecNotFound, code, "'%s' cannot be run in %s: %s", strings.Join(r.Exec, " "), r.Container, last)
+			return
+		case strings.Contains(lower, "cannot connect to the docker daemon") || strings.Contains(lower, "error during connect"):
+			r.fail(kindDocker, code, "cannot reach the Docker API: %s", last)
+			return
+		}
+	}
+	if code > 128 && code < 128+65 {
+		sig := syscall.Signal(code - 128)
+		r.fail(kindSignal, code, "command '%s' was killed by signal %s", r.Command, sig)
+		return
+	}
+	r.fail(kindToolFailure, code, "command '%s' exited with code %d", r.Command, code)
+}
+
+// lastLine returns the last non-empty line of s.
+func lastLine(s string) string {
+	s = strings.TrimRight(s, "\n")
+	return strings.TrimSpace(s[strings.LastIndex(s, "\n")+1:])
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	limit     int
+	buf       []byte
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.limit; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string { return string(b.buf) }
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDockerCLI puts a docker script on PATH that prints its arguments,
+// then behaves as the FAKE_* variables say.
+func fakeDockerCLI(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo "docker $*"
+case " $* " in
+*" timeout "*)
+	if [ -n "$FAKE_NO_TIMEOUT" ]; then
+		echo 'OCI runtime exec failed: exec failed: unable to start container process: exec: "timeout": executable file not found in $PATH: unknown' >&2
+		exit 127
+	fi
+	;;
+esac
+[ -n "$FAKE_STDERR" ] && echo "$FAKE_STDERR" >&2
+[ -n "$FAKE_SIGNAL" ] && kill -"$FAKE_SIGNAL" $$
+[ -n "$FAKE_SLEEP" ] && exec sleep "$FAKE_SLEEP"
+exit "${FAKE_EXIT:-0}"
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRunCommandJSON(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	fakeDockerCLI(t)
+	config := &Config{
+		Containers: map[string]ContainerConfig{"php": {Name: "shop-php-1", Paths: PathMap{dir: "/var/www/html"}}},
+		Commands:   map[string]Command{"php": {Container: "php", Exec: []string{"php"}}},
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		wantCode int
+		wantKind string
+		wantMsg  string
+	}{
+		{name: "success", args: []string{"php", "-v"}},
+		{
+			name:     "tool failure",
+			env:      map[string]string{"FAKE_EXIT": "3", "FAKE_STDERR": "PHP Parse error"},
+			args:     []string{"php", dir + "/bad.php"},
+			wantCode: 3, wantKind: kindToolFailure, wantMsg: "command 'php' exited with code 3",
+		},
+		{
+			name:     "container not found",
+			env:      map[string]string{"FAKE_EXIT": "1", "FAKE_STDERR": "Error response from daemon: No such container: shop-php-1"},
+			args:     []string{"php"},
+			wantCode: 1, wantKind: kindContainerNotFound, wantMsg: "container shop-php-1 not found",
+		},
+		{
+			name:     "container stopped",
+			env:      map[string]string{"FAKE_EXIT": "1", "FAKE_STDERR": "Error response from daemon: container 4f2a is not running"},
+			args:     []string{"php"},
+			wantCode: 1, wantKind: kindContainerStopped, wantMsg: "container shop-php-1 is not running",
+		},
+		{
+			name:     "exec not found",
+			env:      map[string]string{"FAKE_EXIT": "127", "FAKE_STDERR": `OCI runtime exec failed: exec failed: unable to start container process: exec: "php": executable file not found in $PATH: unknown`},
+			args:     []string{"php"},
+			wantCode: 127, wantKind: kindExecNotFound, wantMsg: "'php' cannot be run in shop-php-1",
+		},
+		{
+			name:     "tool message is not a daemon error",
+			env:      map[string]string{"FAKE_EXIT": "1", "FAKE_STDERR": "worker is not running"},
+			args:     []string{"php"},
+			wantCode: 1, wantKind: kindToolFailure,
+		},
+		{
+			name:     "killed in the container",
+			env:      map[string]string{"FAKE_EXIT": "137"},
+			args:     []string{"php"},
+			wantCode: 137, wantKind: kindSignal, wantMsg: "killed by signal killed",
+		},
+		{
+			name:     "docker client killed",
+			env:      map[string]string{"FAKE_SIGNAL": "TERM"},
+			args:     []string{"php"},
+			wantCode: 143, wantKind: kindSignal, wantMsg: "killed by signal terminated",
+		},
+		{
+			name:     "timeout",
+			env:      map[string]string{"FAKE_SLEEP": "5", "BRIDGE_TIMEOUT": "100ms"},
+			args:     []string{"php"},
+			wantCode: timeoutExitCode, wantKind: kindTimeout, wantMsg: "timed out after 100ms",
+		},
+		{
+			name:     "invalid timeout",
+			env:      map[string]string{"BRIDGE_TIMEOUT": "soon"},
+			args:     []string{"php"},
+			wantCode: 1, wantKind: kindConfig, wantMsg: "BRIDGE_TIMEOUT: 'soon' is not a duration",
+		},
+		{
+			name:     "not configured",
+			args:     []string{"bridge-no-such-command"},
+			wantCode: 127, wantKind: kindConfig, wantMsg: "not found in config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			var buf bytes.Buffer
+			code := runCommandJSON(config, tt.args, &buf)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			var r runResult
+			if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+				t.Fatalf("output is not one JSON document: %v\n%s", err, buf.String())
+			}
+			if r.ExitCode != tt.wantCode || r.Command != tt.args[0] {
+				t.Errorf("result = %+v", r)
+			}
+			switch {
+			case tt.wantKind == "" && r.Error != nil:
+				t.Errorf("unexpected error %+v", r.Error)
+			case tt.wantKind != "" && (r.Error == nil || r.Error.Kind != tt.wantKind || !contains(r.Error.Message, tt.wantMsg)):
+				t.Errorf("error = %+v, want kind %s with message containing %q", r.Error, tt.wantKind, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestRunCommandJSONResult(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	fakeDockerCLI(t)
+	t.Setenv("FAKE_STDERR", "warning: deprecated")
+	config := &Config{
+		Containers: map[string]ContainerConfig{"php": {Name: "shop-php-1", Paths: PathMap{dir: "/var/www/html"}}},
+		Commands:   map[string]Command{"php": {Container: "php", Exec: []string{"php", "-d", "memory_limit=-1"}}},
+	}
+
+	var buf bytes.Buffer
+	runCommandJSON(config, []string{"php", dir + "/a.php"}, &buf)
+	var r runResult
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+
+	if r.Container != "shop-php-1" || r.Workdir != "/var/www/html" {
+		t.Errorf("container, workdir = %s, %s", r.Container, r.Workdir)
+	}
+	if strings.Join(r.Exec, " ") != "php -d memory_limit=-1" || strings.Join(r.Args, " ") != "/var/www/html/a.php" {
+		t.Errorf("exec, args = %q, %q", r.Exec, r.Args)
+	}
+	if want := "docker exec -i -w /var/www/html shop-php-1 php -d memory_limit=-1 /var/www/html/a.php\n"; r.Stdout != want {
+		t.Errorf("stdout = %q, want %q (no TTY when capturing)", r.Stdout, want)
+	}
+	if r.Stderr != "warning: deprecated\n" {
+		t.Errorf("stderr = %q", r.Stderr)
+	}
+	if r.DurationMs < 0 || r.Error != nil {
+		t.Errorf("result = %+v", r)
+	}
+
+	// With a timeout the tool is also stopped in the container
+	t.Setenv("BRIDGE_TIMEOUT", "1.5s")
+	buf.Reset()
+	runCommandJSON(config, []string{"php"}, &buf)
+	r = runResult{}
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if want := "docker exec -i -w /var/www/html shop-php-1 timeout -s KILL 1.5 php -d memory_limit=-1\n"; r.Stdout != want {
+		t.Errorf("stdout = %q, want %q", r.Stdout, want)
+	}
+	if strings.Join(r.Exec, " ") != "php -d memory_limit=-1" {
+		t.Errorf("exec = %q, want the configured exec", r.Exec)
+	}
+
+	// Without timeout(1) in the container the tool runs as is, with a warning
+	t.Setenv("FAKE_NO_TIMEOUT", "1")
+	buf.Reset()
+	code := runCommandJSON(config, []string{"php"}, &buf)
+	r = runResult{}
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if want := "docker exec -i -w /var/www/html shop-php-1 php -d memory_limit=-1\n"; r.Stdout != want {
+		t.Errorf("stdout = %q, want %q", r.Stdout, want)
+	}
+	if code != 0 || r.Error != nil {
+		t.Errorf("exit code, error = %d, %+v, want a successful run", code, r.Error)
+	}
+	if len(r.Warnings) != 1 || !contains(r.Warnings[0], "'timeout' cannot be run in shop-php-1") {
+		t.Errorf("warnings = %q", r.Warnings)
+	}
+	t.Setenv("FAKE_NO_TIMEOUT", "")
+	t.Setenv("BRIDGE_TIMEOUT", "")
+
+	// Output over the limit keeps its end
+	t.Setenv("BRIDGE_OUTPUT_LIMIT", "10")
+	buf.Reset()
+	runCommandJSON(config, []string{"php"}, &buf)
+	r = runResult{}
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if r.Stdout != "_limit=-1\n" || !r.StdoutTruncated || r.Stderr != "eprecated\n" || !r.StderrTruncated {
+		t.Errorf("stdout, stderr = %q (%v), %q (%v)", r.Stdout, r.StdoutTruncated, r.Stderr, r.StderrTruncated)
+	}
+}
+
+func TestConfigErrorResult(t *testing.T) {
+	var buf bytes.Buffer
+	configErrorResult([]string{"npm", "test"}, os.ErrNotExist).write(&buf)
+	want := `{
+  "command": "npm",
+  "args": [
+    "test"
+  ],
+  "exit_code": 1,
+  "duration_ms": 0,
+  "stdout": "",
+  "stderr": "",
+  "error": {
+    "kind": "config",
+    "message": "file does not exist"
+  }
+}
+`
+	if buf.String() != want {
+		t.Errorf("result =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{limit: 5}
+	b.Write([]byte("abc"))
+	if b.String() != "abc" || b.truncated {
+		t.Errorf("buffer = %q (truncated %v)", b.String(), b.truncated)
+	}
+	b.Write([]byte("defg"))
+	if b.String() != "cdefg" || !b.truncated {
+		t.Errorf("buffer = %q (truncated %v), want cdefg truncated", b.String(), b.truncated)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Route overrides parts of a command for invocations whose leading arguments
+// match. Routes are evaluated in order and the first match wins; empty fields
+// fall back to the base command definition.
+type Route struct {
+	Match     RouteMatch        `yaml:"match"`
+	Container string            `yaml:"container"`
+	Exec      []string          `yaml:"exec"`
+	Workdir   string            `yaml:"workdir"`
+	Env       map[string]string `yaml:"env"`
+	Paths     PathMap           `yaml:"paths"`
+}
+
+// RouteMatch describes how a route matches the leading arguments.
+// Exactly one of Prefix, Glob or Regex must be set.
+//   - Prefix: each element must equal the argument at the same position
+//   - Glob: each element is a shell pattern matched against the argument at the same position
+//   - Regex: matched at the start of all arguments joined with single spaces,
+//     so "test" matches "test --watch" but not "run test" or "--no-test"
+type RouteMatch struct {
+	Prefix []string `yaml:"prefix"`
+	Glob   []string `yaml:"glob"`
+	Regex  string   `yaml:"regex"`
+}
+
+// Validate checks that the match has exactly one valid matcher.
+func (m *RouteMatch) Validate() error {
+	set := 0
+	if len(m.Prefix) > 0 {
+		set++
+	}
+	if len(m.Glob) > 0 {
+		set++
+	}
+	if m.Regex != "" {
+		set++
+	}
+	if set == 0 {
+		return fmt.Errorf("missing match (set one of 'prefix', 'glob' or 'regex')")
+	}
+	if set > 1 {
+		return fmt.Errorf("only one of 'prefix', 'glob' or 'regex' may be set")
+	}
+
+	for _, pattern := range m.Glob {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob '%s': %w", pattern, err)
+		}
+	}
+	if m.Regex != "" {
+		if _, err := routeRegexp(m.Regex); err != nil {
+			return fmt.Errorf("invalid regex '%s': %w", m.Regex, err)
+		}
+	}
+	return nil
+}
+
+// routeRegexps holds the compiled route regexes by pattern, so a pattern
+// is compiled once, when the config is validated, rather than per match.
+var routeRegexps = struct {
+	sync.Mutex
+	m map[string]*regexp.Regexp
+}{m: make(map[string]*regexp.Regexp)}
+
+// routeRegexp returns the compiled form of a route regex, anchored at the
+// start of the arguments.
+func routeRegexp(pattern string) (*regexp.Regexp, error) {
+	routeRegexps.Lock()
+	defer routeRegexps.Unlock()
+	if re, ok := routeRegexps.m[pattern]; ok {
+		return re, nil
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+	re := regexp.MustCompile(`^(?:` + pattern + `)`)
+	routeRegexps.m[pattern] = re
+	return re, nil
+}
+
+// Matches reports whether the given arguments satisfy the match.
+func (m *RouteMatch) Matches(args []string) bool {
+	switch {
+	case len(m.Prefix) > 0:
+		if len(args) < len(m.Prefix) {
+			return false
+		}
+		for i, want := range m.Prefix {
+			if args[i] != want {
+				return false
+			}
+		}
+		return true
+	case len(m.Glob) > 0:
+		if len(args) < len(m.Glob) {
+			return false
+		}
+		for i, pattern := range m.Glob {
+			if ok, _ := path.Match(pattern, args[i]); !ok {
+				return false
+			}
+		}
+		return true
+	case m.Regex != "":
+		re, err := routeRegexp(m.Regex)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(strings.Join(args, " "))
+	}
+	return false
+}
+
+// ResolveRoute returns the effective command for the given arguments.
+// The first matching route's non-empty fields override the base command;
+// env entries are merged on top of the base env. The returned index is the
+// position of the matched route, or -1 when the base definition is used.
+func (cmd *Command) ResolveRoute(args []string) (Command, int) {
+	for i, route := range cmd.Routes {
+		if !route.Match.Matches(args) {
+			continue
+		}
+
+		resolved := *cmd
+		resolved.Routes = nil
+		if route.Container != "" {
+			resolved.Container = route.Container
+		}
+		if route.Exec != nil {
+			resolved.Exec = route.Exec
+		}
+		if route.Workdir != "" {
+			resolved.Workdir = route.Workdir
+		}
+		if route.Paths != nil {
+			resolved.Paths = route.Paths
+		}
+		if len(route.Env) > 0 {
+			env := make(map[string]string, len(cmd.Env)+len(route.Env))
+			for k, v := range cmd.Env {
+				env[k] = v
+			}
+			for k, v := range route.Env {
+				env[k] = v
+			}
+			resolved.Env = env
+		}
+		return resolved, i
+	}
+	return *cmd, -1
+}
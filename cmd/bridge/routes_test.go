@@ -0,0 +1,176 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRouteMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		match    RouteMatch
+		args     []string
+		expected bool
+	}{
+		{
+			name:     "prefix matches leading args",
+			match:    RouteMatch{Prefix: []string{"run", "build"}},
+			args:     []string{"run", "build", "--watch"},
+			expected: true,
+		},
+		{
+			name:     "prefix does not match different arg",
+			match:    RouteMatch{Prefix: []string{"run", "build"}},
+			args:     []string{"run", "lint"},
+			expected: false,
+		},
+		{
+			name:     "prefix longer than args",
+			match:    RouteMatch{Prefix: []string{"run", "build"}},
+			args:     []string{"run"},
+			expected: false,
+		},
+		{
+			name:     "glob matches per position",
+			match:    RouteMatch{Glob: []string{"play*"}},
+			args:     []string{"playwright", "test"},
+			expected: true,
+		},
+		{
+			name:     "glob does not match",
+			match:    RouteMatch{Glob: []string{"play*"}},
+			args:     []string{"vitest"},
+			expected: false,
+		},
+		{
+			name:     "regex matches joined args",
+			match:    RouteMatch{Regex: `^(test|t)\b`},
+			args:     []string{"test", "--coverage"},
+			expected: true,
+		},
+		{
+			name:     "regex does not match",
+			match:    RouteMatch{Regex: `^test`},
+			args:     []string{"run", "test"},
+			expected: false,
+		},
+		{
+			name:     "regex is anchored at the first argument",
+			match:    RouteMatch{Regex: `test`},
+			args:     []string{"test", "--watch"},
+			expected: true,
+		},
+		{
+			name:     "regex does not match inside an argument",
+			match:    RouteMatch{Regex: `test`},
+			args:     []string{"--no-test"},
+			expected: false,
+		},
+		{
+			name:     "regex does not match a later argument",
+			match:    RouteMatch{Regex: `test`},
+			args:     []string{"run", "test"},
+			expected: false,
+		},
+		{
+			name:     "regex alternation is anchored as a whole",
+			match:    RouteMatch{Regex: `lint|test`},
+			args:     []string{"run", "test"},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.match.Matches(tt.args); got != tt.expected {
+				t.Errorf("Matches(%v) = %v, want %v", tt.args, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRouteMatchValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		match         RouteMatch
+		errorContains string
+	}{
+		{name: "prefix only", match: RouteMatch{Prefix: []string{"test"}}},
+		{name: "empty match", match: RouteMatch{}, errorContains: "missing match"},
+		{name: "multiple matchers", match: RouteMatch{Prefix: []string{"a"}, Regex: "b"}, errorContains: "only one of"},
+		{name: "bad glob", match: RouteMatch{Glob: []string{"[a"}}, errorContains: "invalid glob"},
+		{name: "bad regex", match: RouteMatch{Regex: "("}, errorContains: "invalid regex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.match.Validate()
+			if tt.errorContains == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.errorContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errorContains, err)
+			}
+		})
+	}
+}
+
+func TestResolveRoute(t *testing.T) {
+	cmd := Command{
+		Container: "node",
+		Exec:      []string{"npm"},
+		Workdir:   "/app",
+		Env:       map[string]string{"CI": "1"},
+		Routes: []Route{
+			{
+				Match:     RouteMatch{Prefix: []string{"run", "build"}},
+				Container: "node-build",
+			},
+			{
+				Match:     RouteMatch{Prefix: []string{"test"}},
+				Container: "node-test",
+				Env:       map[string]string{"NODE_ENV": "test"},
+			},
+			{
+				Match:     RouteMatch{Glob: []string{"t*"}},
+				Container: "never-reached-for-test",
+			},
+		},
+	}
+
+	resolved, idx := cmd.ResolveRoute([]string{"run", "build"})
+	if idx != 0 || resolved.Container != "node-build" || resolved.Workdir != "/app" {
+		t.Errorf("run build: got container %q workdir %q idx %d", resolved.Container, resolved.Workdir, idx)
+	}
+
+	resolved, idx = cmd.ResolveRoute([]string{"test", "--watch"})
+	if idx != 1 || resolved.Container != "node-test" {
+		t.Errorf("test: got container %q idx %d", resolved.Container, idx)
+	}
+	if resolved.Env["CI"] != "1" || resolved.Env["NODE_ENV"] != "test" {
+		t.Errorf("test: env not merged: %v", resolved.Env)
+	}
+	if _, ok := cmd.Env["NODE_ENV"]; ok {
+		t.Errorf("base env was modified: %v", cmd.Env)
+	}
+
+	resolved, idx = cmd.ResolveRoute([]string{"install"})
+	if idx != -1 || resolved.Container != "node" {
+		t.Errorf("install: got container %q idx %d", resolved.Container, idx)
+	}
+}
+
+func TestEnvArgs(t *testing.T) {
+	got := envArgs(map[string]string{"B": "2", "A": "1"})
+	want := []string{"-e", "A=1", "-e", "B=2"}
+	if len(got) != len(want) {
+		t.Fatalf("envArgs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("envArgs()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
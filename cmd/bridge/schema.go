@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// schemaDocs describes the config types and their fields for the generated
+// JSON Schema. Type descriptions are keyed by type name and field
+// descriptions by "<Type>.<yaml key>". Every field must be described.
+var schemaDocs = map[string]string{
+	"Config":                   "Bridge configuration. Layers, include: files and bridge.d fragments are merged, so a single file need not be complete.",
+	"Config.version":           "Schema version. The schema describes version 2 only; convert version 1 files with bridge config migrate.",
+	"Config.default_container": "Main container of the project, for reference. Routing does not use it: commands not listed in commands run natively.",
+	"Config.containers":        "Logical container names mapped to actual containers. Set an entry to null to delete an inherited one.",
+	"Config.commands":          "Commands routed to containers, keyed by command name. Set an entry to null to delete an inherited one.",
+	"Config.scopes":            "Command tables that apply when the working directory is under an absolute path prefix.",
+	"Config.profiles":          "Named overlays selected with --profile or BRIDGE_PROFILE.",
+	"Config.presets":           "Built-in command sets to include, keyed by preset name: laravel, symfony, node, go, python or rails. Commands defined under commands override the preset's.",
+
+	"Command":             "How a command is run in a container.",
+	"Command.container":   "Logical or actual container name.",
+	"Command.exec":        "Program and leading arguments run in the container, as a list.",
+	"Command.workdir":     "Working directory in the container when the current directory is not covered by paths.",
+	"Command.paths":       "Host path prefixes mapped to container paths, applied to arguments and the working directory. \"auto\" infers them from the bind mounts shared with the container.",
+	"Command.env":         "Environment variables set for the command.",
+	"Command.routes":      "Overrides for invocations whose arguments match. The first matching route wins.",
+	"Command.select_by":   "Pick the container from the toolchain version the project asks for.",
+	"Command.args_prefix": "Arguments inserted before the user's arguments.",
+	"Command.args_suffix": "Arguments appended after the user's arguments.",
+	"Command.template":    "Argument template with {{args}}, {{1}}, {{2}}, ... and {{cwd}} placeholders.",
+	"Command.user":        "User to run the command as (docker exec -u).",
+	"Command.extends":     "Command whose settings this command inherits.",
+
+	"ContainerConfig":         "The actual container and defaults for every command routed to it.",
+	"ContainerConfig.name":    "Actual container name. Defaults to the logical name.",
+	"ContainerConfig.workdir": "Default working directory.",
+	"ContainerConfig.paths":   "Default path mappings, or \"auto\" to infer them from the container's mounts.",
+	"ContainerConfig.env":     "Environment variables merged into each command's env.",
+	"ContainerConfig.user":    "Default user.",
+
+	"PresetParams":           "Parameters adapting a preset to the project.",
+	"PresetParams.container": "Container for the preset's commands. Defaults to the preset's own name for it, such as php or node.",
+	"PresetParams.app_path":  "Working directory of the application in the container.",
+
+	"Route":           "Override for invocations whose leading arguments match.",
+	"Route.match":     "How the route matches the arguments.",
+	"Route.container": "Container for matching invocations.",
+	"Route.exec":      "Program and leading arguments for matching invocations.",
+	"Route.workdir":   "Working directory for matching invocations.",
+	"Route.env":       "Environment variables merged over the command's env.",
+	"Route.paths":     "Path mappings for matching invocations.",
+
+	"RouteMatch":        "Exactly one of prefix, glob or regex.",
+	"RouteMatch.prefix": "Each element must equal the argument at the same position.",
+	"RouteMatch.glob":   "Shell patterns matched against the arguments at the same positions.",
+	"RouteMatch.regex":  "Regular expression matched at the start of the arguments joined with spaces.",
+
+	"SelectBy":            "Container selection by toolchain version.",
+	"SelectBy.tool":       "Toolchain whose version hints are read from project files.",
+	"SelectBy.candidates": "Containers to choose from, first match wins.",
+
+	"Candidate":           "A container providing a toolchain version.",
+	"Candidate.container": "Container to use when the version matches.",
+	"Candidate.version":   "Toolchain version the container provides.",
+
+	"Scope":          "Commands for a directory tree.",
+	"Scope.commands": "Commands that take precedence over top-level commands in this scope.",
+
+	"Profile":                   "Overlay applied on top of the base config.",
+	"Profile.default_container": "Replaces default_container.",
+	"Profile.containers":        "Containers merged by logical name.",
+	"Profile.commands":          "Commands replaced by name.",
+}
+
+// schemaEnums lists the allowed values of enumerated fields.
+var schemaEnums = map[string]func() []string{
+	"Config.version": func() []string { return []string{currentVersion} },
+	"SelectBy.tool": func() []string {
+		tools := make([]string, 0, len(toolSources))
+		for tool := range toolSources {
+			tools = append(tools, tool)
+		}
+		sort.Strings(tools)
+		return tools
+	},
+}
+
+// schemaRequired lists, per type, the constraints on which fields must be
+// set, mirroring Validate. Top-level fields are not required because a layer
+// or fragment may hold only part of the config.
+var schemaRequired = map[string]map[string]interface{}{
+	"Command": {"anyOf": []interface{}{
+		map[string]interface{}{"required": []string{"extends"}},
+		map[string]interface{}{"required": []string{"container", "exec"}},
+	}},
+	"Route": {"required": []string{"match"}},
+	"RouteMatch": {"oneOf": []interface{}{
+		map[string]interface{}{"required": []string{"prefix"}},
+		map[string]interface{}{"required": []string{"glob"}},
+		map[string]interface{}{"required": []string{"regex"}},
+	}},
+	"SelectBy":  {"required": []string{"tool", "candidates"}},
+	"Candidate": {"required": []string{"container", "version"}},
+}
+
+// configSchema returns the JSON Schema for bridge.yaml, generated from the
+// yaml tags of Config and the types it contains.
+func configSchema() map[string]interface{} {
+	g := &schemaGenerator{defs: make(map[string]interface{})}
+	root := g.structSchema(reflect.TypeOf(Config{}))
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "bridge.yaml"
+	root["$defs"] = g.defs
+
+	// Commands named like a preset's may override single fields of the
+	// preset's command, so they are not held to Command's requirements.
+	// Whether the preset is in use is checked when loading.
+	override := make(map[string]interface{})
+	for k, v := range g.defs["Command"].(map[string]interface{}) {
+		if _, required := schemaRequired["Command"][k]; !required {
+			override[k] = v
+		}
+	}
+	override["description"] = "Override of a preset's command. Fields not set keep the preset's values."
+	g.defs["PresetCommand"] = override
+	overrides := make(map[string]interface{})
+	for _, name := range presetNames() {
+		for _, cmd := range presetCommandNames(name) {
+			overrides[cmd] = map[string]interface{}{"anyOf": []interface{}{
+				map[string]interface{}{"$ref": "#/$defs/PresetCommand"},
+				map[string]interface{}{"type": "null"},
+			}}
+		}
+	}
+	root["properties"].(map[string]interface{})["commands"].(map[string]interface{})["properties"] = overrides
+
+	// include: is consumed while loading and has no field in Config
+	root["properties"].(map[string]interface{})["include"] = map[string]interface{}{
+		"description": "Files to load as part of this one, relative to it. Globs are allowed.",
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	}
+	return root
+}
+
+// writeSchema writes the JSON Schema as indented JSON.
+func writeSchema(w io.Writer) error {
+	data, err := json.MarshalIndent(configSchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// schemaGenerator builds schemas for Go types, collecting struct types
+// under $defs.
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+// typeSchema returns the schema for a field of type t.
+func (g *schemaGenerator) typeSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = nil // Reserve the name for recursive types
+			g.defs[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	case reflect.Map:
+		if t == reflect.TypeOf(PathMap{}) {
+			mappings := map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}}
+			return map[string]interface{}{"anyOf": []interface{}{mappings, map[string]interface{}{"const": autoPaths}}}
+		}
+		values := g.typeSchema(t.Elem())
+		if _, isRef := values["$ref"]; isRef {
+			// Section entries can be set to null to delete an inherited entry
+			values = map[string]interface{}{"anyOf": []interface{}{values, map[string]interface{}{"type": "null"}}}
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// structSchema returns the object schema for a struct type.
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	fields := yamlFields(t)
+	props := make(map[string]interface{}, len(fields))
+	for key, ft := range fields {
+		prop := g.typeSchema(ft)
+		if doc := schemaDocs[t.Name()+"."+key]; doc != "" {
+			prop["description"] = doc
+		}
+		if enum, ok := schemaEnums[t.Name()+"."+key]; ok {
+			prop["enum"] = enum()
+		}
+		props[key] = prop
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if doc := schemaDocs[t.Name()]; doc != "" {
+		schema["description"] = doc
+	}
+	for k, v := range schemaRequired[t.Name()] {
+		schema[k] = v
+	}
+	return schema
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// publishedSchemaPath is the checked-in schema editors can point at.
+// Regenerate it with: go run ./cmd/bridge config schema > examples/bridge.schema.json
+const publishedSchemaPath = "../../examples/bridge.schema.json"
+
+// schemaStructTypes returns every struct type reachable from Config, by name.
+func schemaStructTypes() map[string]reflect.Type {
+	types := make(map[string]reflect.Type)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		if _, ok := types[t.Name()]; ok {
+			return
+		}
+		types[t.Name()] = t
+		for _, ft := range yamlFields(t) {
+			walk(ft)
+		}
+	}
+	walk(reflect.TypeOf(Config{}))
+	return types
+}
+
+func TestSchemaDocumentsEveryField(t *testing.T) {
+	types := schemaStructTypes()
+
+	for name, typ := range types {
+		if schemaDocs[name] == "" {
+			t.Errorf("schemaDocs has no description for type %s", name)
+		}
+		for key := range yamlFields(typ) {
+			if schemaDocs[name+"."+key] == "" {
+				t.Errorf("schemaDocs has no description for %s.%s", name, key)
+			}
+		}
+	}
+
+	for key := range schemaDocs {
+		typeName, field, isField := strings.Cut(key, ".")
+		typ, ok := types[typeName]
+		if !ok {
+			t.Errorf("schemaDocs entry %q refers to unknown type %s", key, typeName)
+			continue
+		}
+		if _, ok := yamlFields(typ)[field]; isField && !ok {
+			t.Errorf("schemaDocs entry %q refers to unknown field", key)
+		}
+	}
+}
+
+func TestSchemaMatchesStructTags(t *testing.T) {
+	schema := configSchema()
+	defs := schema["$defs"].(map[string]interface{})
+
+	for name, typ := range schemaStructTypes() {
+		def := schema
+		if name != "Config" {
+			d, ok := defs[name].(map[string]interface{})
+			if !ok {
+				t.Errorf("schema has no definition for %s", name)
+				continue
+			}
+			def = d
+		}
+
+		var got, want []string
+		for key := range def["properties"].(map[string]interface{}) {
+			if name == "Config" && key == "include" {
+				continue
+			}
+			got = append(got, key)
+		}
+		for key := range yamlFields(typ) {
+			want = append(want, key)
+		}
+		sort.Strings(got)
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s properties = %v, want %v", name, got, want)
+		}
+	}
+
+	for key := range schemaEnums {
+		typeName, field, _ := strings.Cut(key, ".")
+		if _, ok := yamlFields(schemaStructTypes()[typeName])[field]; !ok {
+			t.Errorf("schemaEnums entry %q refers to unknown field", key)
+		}
+	}
+}
+
+func TestSchemaEnums(t *testing.T) {
+	data, err := json.Marshal(configSchema())
+	if err != nil {
+		t.Fatalf("failed to marshal schema: %v", err)
+	}
+	for _, want := range []string{`"enum":["2"]`, `"enum":["go","node","php"]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("schema does not contain %s", want)
+		}
+	}
+}
+
+func TestSchemaPresetCommandOverrides(t *testing.T) {
+	schema := configSchema()
+	defs := schema["$defs"].(map[string]interface{})
+	if _, ok := defs["Command"].(map[string]interface{})["anyOf"]; !ok {
+		t.Error("Command should require extends or container and exec")
+	}
+	if _, ok := defs["PresetCommand"].(map[string]interface{})["anyOf"]; ok {
+		t.Error("PresetCommand should not require any field")
+	}
+
+	commands := schema["properties"].(map[string]interface{})["commands"].(map[string]interface{})
+	props := commands["properties"].(map[string]interface{})
+	for _, name := range []string{"phpunit", "npm", "rspec"} {
+		data, _ := json.Marshal(props[name])
+		if !strings.Contains(string(data), `"$ref":"#/$defs/PresetCommand"`) {
+			t.Errorf("commands.%s = %s, want a PresetCommand reference", name, data)
+		}
+	}
+	if _, ok := props["deploy"]; ok {
+		t.Error("only preset command names may be partial")
+	}
+}
+
+func TestPublishedSchemaUpToDate(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSchema(&buf); err != nil {
+		t.Fatalf("failed to write schema: %v", err)
+	}
+
+	published, err := os.ReadFile(publishedSchemaPath)
+	if err != nil {
+		t.Fatalf("failed to read published schema: %v", err)
+	}
+	if !bytes.Equal(published, buf.Bytes()) {
+		t.Errorf("%s is out of date; regenerate it with: go run ./cmd/bridge config schema > examples/bridge.schema.json", publishedSchemaPath)
+	}
+}
+
+func TestExampleConfigLoads(t *testing.T) {
+	if _, err := LoadConfig("../../"+exampleConfigPath, ""); err != nil {
+		t.Errorf("example config does not load: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// Scope overrides the command table for invocations whose working directory
+// is at or below the scope's path prefix. Commands not defined in the scope
+// fall back to the enclosing scopes, then to the top-level commands.
+type Scope struct {
+	Commands map[string]Command `yaml:"commands"`
+}
+
+// hasPathPrefix reports whether path equals prefix or is nested below it.
+// Unlike strings.HasPrefix, /srv/app does not match /srv/application.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return strings.HasPrefix(path, "/")
+	}
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
+}
+
+// ScopeFor returns the longest scope prefix that contains cwd.
+// Returns ("", nil) if no scope matches.
+func (c *Config) ScopeFor(cwd string) (string, *Scope) {
+	prefixes := c.ScopesFor(cwd)
+	if len(prefixes) == 0 {
+		return "", nil
+	}
+	scope := c.Scopes[prefixes[0]]
+	return prefixes[0], &scope
+}
+
+// ScopesFor returns the prefixes of every scope that contains cwd, longest
+// (innermost) first.
+func (c *Config) ScopesFor(cwd string) []string {
+	var prefixes []string
+	for prefix := range c.Scopes {
+		if hasPathPrefix(cwd, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if len(prefixes[i]) != len(prefixes[j]) {
+			return len(prefixes[i]) > len(prefixes[j])
+		}
+		return prefixes[i] < prefixes[j]
+	})
+	return prefixes
+}
+
+// commandScope returns the innermost scope containing cwd that defines
+// name. Returns ("", nil) if none does.
+func (c *Config) commandScope(name, cwd string) (string, *Scope) {
+	for _, prefix := range c.ScopesFor(cwd) {
+		scope := c.Scopes[prefix]
+		if _, ok := scope.Commands[name]; ok {
+			return prefix, &scope
+		}
+	}
+	return "", nil
+}
+
+// LookupCommand finds the command definition for name, preferring the
+// scopes matching cwd, innermost first, over the top-level command table.
+func (c *Config) LookupCommand(name, cwd string) (Command, bool) {
+	if _, scope := c.commandScope(name, cwd); scope != nil {
+		return scope.Commands[name], true
+	}
+	cmd, ok := c.Commands[name]
+	return cmd, ok
+}
+
+// currentDir returns the current working directory, or "" if it cannot be determined.
+func currentDir() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return cwd
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		path     string
+		prefix   string
+		expected bool
+	}{
+		{"/workspace/services/billing", "/workspace/services/billing", true},
+		{"/workspace/services/billing/cmd", "/workspace/services/billing", true},
+		{"/workspace/services/billing/cmd", "/workspace/services/billing/", true},
+		{"/workspace/services/billing2", "/workspace/services/billing", false},
+		{"/workspace", "/workspace/services", false},
+		{"/anything", "/", true},
+	}
+
+	for _, tt := range tests {
+		if got := hasPathPrefix(tt.path, tt.prefix); got != tt.expected {
+			t.Errorf("hasPathPrefix(%q, %q) = %v, want %v", tt.path, tt.prefix, got, tt.expected)
+		}
+	}
+}
+
+func TestLookupCommand(t *testing.T) {
+	config := &Config{
+		Version: "1",
+		Commands: map[string]Command{
+			"go":  {Container: "golang", Exec: []string{"go"}},
+			"npm": {Container: "node", Exec: []string{"npm"}},
+		},
+		Scopes: map[string]Scope{
+			"/workspace/services": {
+				Commands: map[string]Command{
+					"go":   {Container: "services-go", Exec: []string{"go"}},
+					"make": {Container: "services-tools", Exec: []string{"make"}},
+				},
+			},
+			"/workspace/services/billing": {
+				Commands: map[string]Command{
+					"go": {Container: "billing-go", Exec: []string{"go"}, Workdir: "/app"},
+				},
+			},
+			"/workspace/services/search": {
+				Commands: map[string]Command{
+					"go": {Container: "search-go", Exec: []string{"go"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		cmdName           string
+		cwd               string
+		expectedContainer string
+		expectedFound     bool
+	}{
+		{"billing scope", "go", "/workspace/services/billing/internal", "billing-go", true},
+		{"search scope", "go", "/workspace/services/search", "search-go", true},
+		{"parent scope for sibling", "go", "/workspace/services/auth", "services-go", true},
+		{"no scope uses top-level", "go", "/workspace/docs", "golang", true},
+		{"scope falls back for undefined command", "npm", "/workspace/services/billing", "node", true},
+		{"nested scope falls back to enclosing scope", "make", "/workspace/services/billing/internal", "services-tools", true},
+		{"unknown command", "cargo", "/workspace/services/billing", "", false},
+		{"empty cwd uses top-level", "go", "", "golang", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, found := config.LookupCommand(tt.cmdName, tt.cwd)
+			if found != tt.expectedFound {
+				t.Fatalf("found = %v, want %v", found, tt.expectedFound)
+			}
+			if cmd.Container != tt.expectedContainer {
+				t.Errorf("container = %q, want %q", cmd.Container, tt.expectedContainer)
+			}
+		})
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	config := &Config{
+		Version:  "1",
+		Commands: map[string]Command{"go": {Container: "golang", Exec: []string{"go"}}},
+		Scopes: map[string]Scope{
+			"services/billing": {Commands: map[string]Command{"go": {Container: "billing-go", Exec: []string{"go"}}}},
+		},
+	}
+	if err := config.Validate(); err == nil || !contains(err.Error(), "must be absolute") {
+		t.Errorf("expected absolute path error, got %v", err)
+	}
+
+	config.Scopes = map[string]Scope{
+		"/workspace/services/billing": {Commands: map[string]Command{"go": {Exec: []string{"go"}}}},
+	}
+	err := config.Validate()
+	if err == nil || !contains(err.Error(), "scope '/workspace/services/billing': command 'go'") {
+		t.Errorf("expected scoped command error, got %v", err)
+	}
+}
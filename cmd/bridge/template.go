@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// placeholderPattern matches {{name}} placeholders in command templates.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// templateWord is a single argv element of a parsed template.
+type templateWord struct {
+	text   string // Word text with quotes removed, placeholders intact
+	quoted bool   // Word contained quoting; {{args}} is then joined, not spread
+}
+
+// parseTemplate splits a command template into words using shell-like
+// quoting rules and validates its placeholders:
+//   - Unquoted whitespace separates words
+//   - Single quotes preserve whitespace, quotes and backslashes literally
+//   - Double quotes group words; \" and \\ are the only escapes
+//   - Outside quotes, a backslash escapes the next character
+//
+// Supported placeholders are {{args}}, {{cwd}} and positional {{1}}, {{2}}, ...
+// Placeholders are expanded in every word, quoted or not, so that templates
+// such as sh -c 'echo {{1}}' work.
+func parseTemplate(template string) ([]templateWord, error) {
+	var words []templateWord
+	var current strings.Builder
+	inWord, quoted := false, false
+
+	flush := func() {
+		if inWord {
+			words = append(words, templateWord{text: current.String(), quoted: quoted})
+		}
+		current.Reset()
+		inWord, quoted = false, false
+	}
+
+	runes := []rune(template)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		case r == '\'':
+			inWord, quoted = true, true
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote in template")
+			}
+			current.WriteString(string(runes[i+1 : end]))
+			i = end
+		case r == '"':
+			inWord, quoted = true, true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+				current.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated double quote in template")
+			}
+		case r == '\\':
+			inWord = true
+			if i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			}
+		default:
+			inWord = true
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	for _, w := range words {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(w.text, -1) {
+			if err := validatePlaceholder(m[1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return words, nil
+}
+
+// indexRune returns the index of r in runes at or after start, or -1.
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// validatePlaceholder checks that a placeholder name is supported.
+func validatePlaceholder(name string) error {
+	if name == "args" || name == "cwd" {
+		return nil
+	}
+	if n, err := strconv.Atoi(name); err == nil && n >= 1 {
+		return nil
+	}
+	return fmt.Errorf("unknown placeholder '{{%s}}' in template (expected {{args}}, {{cwd}} or {{1}}, {{2}}, ...)", name)
+}
+
+// ValidateTemplate checks the template syntax and placeholders.
+func (cmd *Command) ValidateTemplate() error {
+	if cmd.Template == "" {
+		return nil
+	}
+	_, err := parseTemplate(cmd.Template)
+	return err
+}
+
+// BuildArgs produces the argument list passed to the executable:
+// args_prefix, then the expanded template (or the user's args), then
+// args_suffix. It runs before path translation, so expanded values such as
+// {{cwd}} are translated like any other argument.
+func (cmd *Command) BuildArgs(args []string, cwd string) ([]string, error) {
+	body := args
+	if cmd.Template != "" {
+		words, err := parseTemplate(cmd.Template)
+		if err != nil {
+			return nil, err
+		}
+		body, err = expandTemplate(words, args, cwd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cmd.ArgsPrefix) == 0 && len(cmd.ArgsSuffix) == 0 {
+		return body, nil
+	}
+	result := make([]string, 0, len(cmd.ArgsPrefix)+len(body)+len(cmd.ArgsSuffix))
+	result = append(result, cmd.ArgsPrefix...)
+	result = append(result, body...)
+	result = append(result, cmd.ArgsSuffix...)
+	return result, nil
+}
+
+// expandTemplate substitutes placeholders in parsed template words.
+// {{N}} is the Nth user argument and is required. {{args}} is every argument
+// after the highest referenced position; as a whole unquoted word it expands
+// to separate arguments, otherwise they are joined with spaces. Templates
+// without {{args}} get those remaining arguments appended.
+func expandTemplate(words []templateWord, args []string, cwd string) ([]string, error) {
+	highest := 0
+	hasArgs := false
+	for _, w := range words {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(w.text, -1) {
+			if m[1] == "args" {
+				hasArgs = true
+			} else if n, err := strconv.Atoi(m[1]); err == nil && n > highest {
+				highest = n
+			}
+		}
+	}
+	if highest > len(args) {
+		return nil, fmt.Errorf("template requires at least %d argument(s), got %d", highest, len(args))
+	}
+	rest := args[highest:]
+
+	var result []string
+	for _, w := range words {
+		if m := placeholderPattern.FindStringSubmatch(w.text); !w.quoted && m != nil && m[0] == w.text && m[1] == "args" {
+			result = append(result, rest...)
+			continue
+		}
+
+		expanded := placeholderPattern.ReplaceAllStringFunc(w.text, func(p string) string {
+			name := placeholderPattern.FindStringSubmatch(p)[1]
+			switch name {
+			case "args":
+				return strings.Join(rest, " ")
+			case "cwd":
+				return cwd
+			}
+			n, _ := strconv.Atoi(name)
+			return args[n-1]
+		})
+		result = append(result, expanded)
+	}
+
+	if !hasArgs {
+		result = append(result, rest...)
+	}
+	return result, nil
+}
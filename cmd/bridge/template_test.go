@@ -0,0 +1,168 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name          string
+		template      string
+		expected      []templateWord
+		errorContains string
+	}{
+		{
+			name:     "splits on whitespace",
+			template: "artisan  test --filter={{1}}",
+			expected: []templateWord{{text: "artisan"}, {text: "test"}, {text: "--filter={{1}}"}},
+		},
+		{
+			name:     "double quotes group words",
+			template: `run "a b" "say \"hi\""`,
+			expected: []templateWord{{text: "run"}, {text: "a b", quoted: true}, {text: `say "hi"`, quoted: true}},
+		},
+		{
+			name:     "single quotes are literal",
+			template: `echo 'a \ b'`,
+			expected: []templateWord{{text: "echo"}, {text: `a \ b`, quoted: true}},
+		},
+		{
+			name:     "backslash escapes space",
+			template: `a\ b`,
+			expected: []templateWord{{text: "a b"}},
+		},
+		{
+			name:     "empty quoted word is kept",
+			template: `a ""`,
+			expected: []templateWord{{text: "a"}, {text: "", quoted: true}},
+		},
+		{name: "unknown placeholder", template: "test {{argv}}", errorContains: "unknown placeholder '{{argv}}'"},
+		{name: "zero placeholder", template: "test {{0}}", errorContains: "unknown placeholder"},
+		{name: "unterminated double quote", template: `test "abc`, errorContains: "unterminated double quote"},
+		{name: "unterminated single quote", template: `test 'abc`, errorContains: "unterminated single quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, err := parseTemplate(tt.template)
+			if tt.errorContains != "" {
+				if err == nil || !contains(err.Error(), tt.errorContains) {
+					t.Errorf("expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(words, tt.expected) {
+				t.Errorf("parseTemplate() = %#v, want %#v", words, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		cmd           Command
+		args          []string
+		expected      []string
+		errorContains string
+	}{
+		{
+			name:     "no template passes args through",
+			cmd:      Command{},
+			args:     []string{"-v"},
+			expected: []string{"-v"},
+		},
+		{
+			name:     "prefix and suffix",
+			cmd:      Command{ArgsPrefix: []string{"--colors=never", "-c", "phpunit.xml.dist"}, ArgsSuffix: []string{"--stop-on-failure"}},
+			args:     []string{"tests/Unit"},
+			expected: []string{"--colors=never", "-c", "phpunit.xml.dist", "tests/Unit", "--stop-on-failure"},
+		},
+		{
+			name:     "positional placeholder with remaining args appended",
+			cmd:      Command{Template: "artisan test --filter={{1}}"},
+			args:     []string{"Foo", "--stop-on-failure"},
+			expected: []string{"artisan", "test", "--filter=Foo", "--stop-on-failure"},
+		},
+		{
+			name:     "unquoted args placeholder spreads",
+			cmd:      Command{Template: "run {{args}} --ci"},
+			args:     []string{"a b", "c"},
+			expected: []string{"run", "a b", "c", "--ci"},
+		},
+		{
+			name:     "quoted args placeholder joins",
+			cmd:      Command{Template: `-e "{{args}}"`},
+			args:     []string{"echo", "hi"},
+			expected: []string{"-e", "echo hi"},
+		},
+		{
+			name:     "placeholders expand inside single quotes",
+			cmd:      Command{Template: `-c 'cd {{cwd}} && make {{1}}'`},
+			args:     []string{"test", "-j4"},
+			expected: []string{"-c", "cd /workspace/app && make test", "-j4"},
+		},
+		{
+			name:     "args excludes positional arguments",
+			cmd:      Command{Template: "--group={{1}} {{args}}"},
+			args:     []string{"unit", "--debug"},
+			expected: []string{"--group=unit", "--debug"},
+		},
+		{
+			name:     "cwd placeholder",
+			cmd:      Command{Template: "--root={{cwd}}"},
+			args:     nil,
+			expected: []string{"--root=/workspace/app"},
+		},
+		{
+			name:     "template with prefix",
+			cmd:      Command{ArgsPrefix: []string{"artisan"}, Template: "test --filter={{1}}"},
+			args:     []string{"Foo"},
+			expected: []string{"artisan", "test", "--filter=Foo"},
+		},
+		{
+			name:          "missing positional argument",
+			cmd:           Command{Template: "test --filter={{1}}"},
+			args:          nil,
+			errorContains: "requires at least 1 argument",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cmd.BuildArgs(tt.args, "/workspace/app")
+			if tt.errorContains != "" {
+				if err == nil || !contains(err.Error(), tt.errorContains) {
+					t.Errorf("expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("BuildArgs() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildArgsBeforePathTranslation(t *testing.T) {
+	cmd := Command{
+		Template: "{{cwd}} {{args}}",
+		Paths:    map[string]string{"/workspace": "/var/www/html"},
+	}
+	args, err := cmd.BuildArgs([]string{"/workspace/tests"}, "/workspace")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := cmd.TranslateArgs(args)
+	expected := []string{"/var/www/html", "/var/www/html/tests"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("translated = %q, want %q", got, expected)
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SelectBy picks a container for a command based on the toolchain version
+// the project asks for. Version hints are read from project files found by
+// walking up from the working directory; the first candidate whose version
+// satisfies the hint is used. When no hint is found, the command's own
+// container is used.
+type SelectBy struct {
+	Tool       string      `yaml:"tool"`
+	Candidates []Candidate `yaml:"candidates"`
+}
+
+// Candidate is a container providing a specific toolchain version.
+type Candidate struct {
+	Container string `yaml:"container"`
+	Version   string `yaml:"version"`
+}
+
+// versionHint is a version constraint read from a project file.
+type versionHint struct {
+	Constraint string // Raw constraint, e.g. "^8.1" or ">=1.22"
+	Source     string // File the constraint was read from
+	composer   bool   // Constraint uses Composer tilde semantics
+}
+
+// versionSource is a project file that may carry a version hint. The read
+// function returns "" if the file does not exist or carries no hint.
+type versionSource struct {
+	file string
+	read func(path string) (string, error)
+}
+
+// toolSources lists, per supported tool, the project files consulted in
+// priority order within a single directory.
+var toolSources = map[string][]versionSource{
+	"go": {
+		{file: "go.mod", read: readGoMod},
+		{file: ".tool-versions", read: toolVersionsReader("golang", "go")},
+	},
+	"node": {
+		{file: ".nvmrc", read: readNvmrc},
+		{file: "package.json", read: readPackageEngines},
+		{file: ".tool-versions", read: toolVersionsReader("nodejs", "node")},
+	},
+	"php": {
+		{file: "composer.json", read: readComposerPHP},
+		{file: ".tool-versions", read: toolVersionsReader("php")},
+	},
+}
+
+// Validate checks that the tool is supported and every candidate is usable.
+func (s *SelectBy) Validate() error {
+	if _, ok := toolSources[s.Tool]; !ok {
+		return fmt.Errorf("unsupported tool '%s' (expected one of: go, node, php)", s.Tool)
+	}
+	if len(s.Candidates) == 0 {
+		return fmt.Errorf("missing required field 'candidates'")
+	}
+	for i, c := range s.Candidates {
+		if c.Container == "" {
+			return fmt.Errorf("candidate %d: missing required field 'container'", i)
+		}
+		if _, err := parseVersion(c.Version); err != nil {
+			return fmt.Errorf("candidate %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// SelectContainer returns the container to use for the project rooted at or
+// above dir. It returns fallback when no version hint is found, and an error
+// listing the candidates when a hint is found but none satisfies it. The
+// unusable hints skipped on the way are described in ignored.
+func (s *SelectBy) SelectContainer(dir, fallback string) (container string, hint *versionHint, ignored []string, err error) {
+	hint, ignored, err = findVersionHint(s.Tool, dir)
+	if err != nil {
+		return "", nil, ignored, err
+	}
+	if hint == nil {
+		return fallback, nil, ignored, nil
+	}
+
+	for _, c := range s.Candidates {
+		ok, err := satisfies(c.Version, hint)
+		if err != nil {
+			return "", hint, ignored, fmt.Errorf("%s (from %s): %w", s.Tool, hint.Source, err)
+		}
+		if ok {
+			return c.Container, hint, ignored, nil
+		}
+	}
+
+	listed := make([]string, len(s.Candidates))
+	for i, c := range s.Candidates {
+		listed[i] = fmt.Sprintf("%s (%s)", c.Container, c.Version)
+	}
+	return "", hint, ignored, fmt.Errorf("no container satisfies %s %s (from %s); candidates: %s",
+		s.Tool, hint.Constraint, hint.Source, strings.Join(listed, ", "))
+}
+
+// ResolveToolchain returns the command with its container replaced by the
+// select_by choice for the project containing dir. Commands without
+// select_by are returned unchanged.
+func (cmd *Command) ResolveToolchain(dir string) (Command, error) {
+	resolved, _, _, err := cmd.resolveToolchainHint(dir)
+	return resolved, err
+}
+
+// resolveToolchainHint is ResolveToolchain, also returning the version hint
+// the container was selected by (nil when none was found) and the unusable
+// hints skipped.
+func (cmd *Command) resolveToolchainHint(dir string) (Command, *versionHint, []string, error) {
+	resolved := *cmd
+	if cmd.SelectBy == nil {
+		return resolved, nil, nil, nil
+	}
+	container, hint, ignored, err := cmd.SelectBy.SelectContainer(dir, cmd.Container)
+	if err != nil {
+		return resolved, hint, ignored, err
+	}
+	resolved.Container = container
+	return resolved, hint, ignored, nil
+}
+
+// findVersionHint walks from dir up to the filesystem root and returns the
+// first version hint found for tool. Constraints that cannot be evaluated,
+// such as the "lts/*" or "node" aliases in .nvmrc, count as no hint; each
+// is described in ignored so that explain and doctor can report it.
+func findVersionHint(tool, dir string) (hint *versionHint, ignored []string, err error) {
+	if dir == "" {
+		return nil, nil, nil
+	}
+	for {
+		for _, src := range toolSources[tool] {
+			path := filepath.Join(dir, src.file)
+			constraint, err := src.read(path)
+			if err != nil {
+				return nil, ignored, fmt.Errorf("failed to read version from %s: %w", path, err)
+			}
+			if constraint == "" {
+				continue
+			}
+			hint := &versionHint{
+				Constraint: constraint,
+				Source:     path,
+				composer:   src.file == "composer.json",
+			}
+			if err := hint.check(); err != nil {
+				ignored = append(ignored, fmt.Sprintf("ignoring %s version '%s' in %s: %s", tool, constraint, path, err))
+				continue
+			}
+			return hint, ignored, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ignored, nil
+		}
+		dir = parent
+	}
+}
+
+// readFileIfExists returns the file contents, or nil if it does not exist.
+func readFileIfExists(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// readGoMod returns ">=X" for a go.mod "go X" directive.
+func readGoMod(path string) (string, error) {
+	data, err := readFileIfExists(path)
+	if err != nil || data == nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "go" {
+			return ">=" + fields[1], nil
+		}
+	}
+	return "", nil
+}
+
+// readNvmrc returns the version pinned in .nvmrc.
+func readNvmrc(path string) (string, error) {
+	data, err := readFileIfExists(path)
+	if err != nil || data == nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readPackageEngines returns the engines.node constraint from package.json.
+func readPackageEngines(path string) (string, error) {
+	data, err := readFileIfExists(path)
+	if err != nil || data == nil {
+		return "", err
+	}
+	var pkg struct {
+		Engines map[string]string `json:"engines"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return "", err
+	}
+	return pkg.Engines["node"], nil
+}
+
+// readComposerPHP returns the require.php constraint from composer.json.
+func readComposerPHP(path string) (string, error) {
+	data, err := readFileIfExists(path)
+	if err != nil || data == nil {
+		return "", err
+	}
+	var composer struct {
+		Require map[string]string `json:"require"`
+	}
+	if err := json.Unmarshal(data, &composer); err != nil {
+		return "", err
+	}
+	return composer.Require["php"], nil
+}
+
+// toolVersionsReader returns a reader for asdf/mise .tool-versions files that
+// looks up the first of the given tool names.
+func toolVersionsReader(names ...string) func(string) (string, error) {
+	return func(path string) (string, error) {
+		data, err := readFileIfExists(path)
+		if err != nil || data == nil {
+			return "", err
+		}
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			for _, name := range names {
+				if fields[0] == name {
+					return fields[1], nil
+				}
+			}
+		}
+		return "", nil
+	}
+}
+
+// parseVersion parses a dotted numeric version such as "1.22", "v18" or
+// "8.3.1". A trailing pre-release or build suffix is ignored.
+func parseVersion(s string) ([]int, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, fmt.Errorf("invalid version '%s'", s)
+	}
+	parts := strings.Split(v, ".")
+	nums := make([]int, 0, len(parts))
+	for _, p := range parts {
+		if p == "x" || p == "X" || p == "*" {
+			break
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version '%s'", s)
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+// compareVersions compares two versions, padding missing components with zeros.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// samePrefix reports whether a and b agree on every component both specify.
+func samePrefix(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// check returns an error if the hint's constraint cannot be evaluated.
+func (h *versionHint) check() error {
+	_, err := satisfies("0", h)
+	return err
+}
+
+// spacedOperator matches a comparison operator separated from its version
+// by spaces, as in ">= 18".
+var spacedOperator = regexp.MustCompile(`(>=|<=|[<>=^~])\s+`)
+
+// satisfies reports whether the candidate version satisfies the hint's
+// constraint. Supported syntax: exact or partial versions ("20", "1.22.1"),
+// comparison operators (>=, >, <=, <, =), caret (^) and tilde (~) ranges,
+// wildcards ("8.*"), space or comma separated conjunctions and "||"
+// alternatives (or "|", as Composer also accepts). An operator may be
+// separated from its version by spaces (">= 18").
+func satisfies(version string, hint *versionHint) (bool, error) {
+	v, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+
+	constraint := spacedOperator.ReplaceAllString(hint.Constraint, "$1")
+	alternatives := strings.Split(strings.ReplaceAll(constraint, "||", "|"), "|")
+	for _, alt := range alternatives {
+		terms := strings.FieldsFunc(alt, func(r rune) bool { return r == ' ' || r == ',' })
+		if len(terms) == 0 {
+			continue
+		}
+		all := true
+		for _, term := range terms {
+			ok, err := satisfiesTerm(v, term, hint.composer)
+			if err != nil {
+				return false, fmt.Errorf("invalid constraint '%s': %w", hint.Constraint, err)
+			}
+			if !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// satisfiesTerm evaluates a single constraint term against version v.
+func satisfiesTerm(v []int, term string, composerTilde bool) (bool, error) {
+	if term == "*" {
+		return true, nil
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if !strings.HasPrefix(term, op) {
+			continue
+		}
+		target, err := parseVersion(term[len(op):])
+		if err != nil {
+			return false, err
+		}
+		cmp := compareVersions(v, target)
+		switch op {
+		case ">=":
+			return cmp >= 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		case "<":
+			return cmp < 0, nil
+		case "=":
+			return samePrefix(v, target), nil
+		case "^":
+			return cmp >= 0 && compareVersions(v, caretUpper(target)) < 0, nil
+		case "~":
+			return cmp >= 0 && compareVersions(v, tildeUpper(target, composerTilde)) < 0, nil
+		}
+	}
+
+	// Bare version: partial match ("20" matches any 20.x)
+	target, err := parseVersion(term)
+	if err != nil {
+		return false, err
+	}
+	return samePrefix(v, target), nil
+}
+
+// caretUpper returns the exclusive upper bound of a caret range: the next
+// increment of the first non-zero component.
+func caretUpper(v []int) []int {
+	for i, n := range v {
+		if n != 0 || i == len(v)-1 {
+			upper := append([]int{}, v[:i]...)
+			return append(upper, n+1)
+		}
+	}
+	return []int{1}
+}
+
+// tildeUpper returns the exclusive upper bound of a tilde range. npm allows
+// patch updates (~1.2 < 1.3) while Composer treats the last specified
+// component as the one that may change (~1.2 < 2.0).
+func tildeUpper(v []int, composer bool) []int {
+	if len(v) == 0 {
+		return []int{1}
+	}
+	idx := 1
+	if len(v) == 1 {
+		idx = 0
+	} else if composer {
+		idx = len(v) - 2
+	}
+	upper := append([]int{}, v[:idx]...)
+	return append(upper, v[idx]+1)
+}
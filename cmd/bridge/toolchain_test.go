@@ -0,0 +1,222 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		composer   bool
+		expected   bool
+	}{
+		{"1.24", ">=1.22", false, true},
+		{"1.22", ">=1.22", false, true},
+		{"1.21", ">=1.22", false, false},
+		{"20", "20", false, true},
+		{"20.11", "20", false, true},
+		{"18", "20", false, false},
+		{"1.22", "1.22.1", false, true},
+		{"8.3", "^8.1", true, true},
+		{"9.0", "^8.1", true, false},
+		{"8.0", "^8.1", true, false},
+		{"8.3", "~8.1", true, true},
+		{"8.3", "~8.1", false, false},
+		{"8.1.9", "~8.1", false, true},
+		{"20", ">=18 <21", false, true},
+		{"22", ">=18, <21", false, false},
+		{"8.1", "^7.4 || ^8.0", true, true},
+		{"7.3", "^7.4 || ^8.0", true, false},
+		{"8.1", "^7.4|^8.0", true, true},
+		{"7.3", "^7.4 | ^8.0", true, false},
+		{"8.2", "8.*", false, true},
+		{"0.3", "^0.2", false, false},
+		{"18", "*", false, true},
+		{"18.2", ">= 18", false, true},
+		{"16", ">= 18", false, false},
+		{"19", "<  20", false, true},
+		{"20", "<  20", false, false},
+		{"8.2", "^8.1 || >= 8.2", true, true},
+		{"20", ">= 18, < 21", false, true},
+	}
+
+	for _, tt := range tests {
+		hint := &versionHint{Constraint: tt.constraint, composer: tt.composer}
+		got, err := satisfies(tt.version, hint)
+		if err != nil {
+			t.Errorf("satisfies(%q, %q) unexpected error: %v", tt.version, tt.constraint, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("satisfies(%q, %q, composer=%v) = %v, want %v", tt.version, tt.constraint, tt.composer, got, tt.expected)
+		}
+	}
+}
+
+func TestSatisfiesInvalidConstraint(t *testing.T) {
+	_, err := satisfies("20", &versionHint{Constraint: "lts/iron"})
+	if err == nil || !contains(err.Error(), "invalid constraint") {
+		t.Errorf("expected invalid constraint error, got %v", err)
+	}
+}
+
+func TestFindVersionHint(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	writeFile("go.mod", "module example.com/app\n\ngo 1.22\n")
+	writeFile("services/web/package.json", `{"engines": {"node": ">=18"}}`)
+	writeFile("services/web/.nvmrc", "20\n")
+	writeFile("services/api/composer.json", `{"require": {"php": "^8.1"}}`)
+	writeFile("tools/.tool-versions", "# pinned\nnodejs 18.19.0\ngolang 1.24.1\n")
+	if err := os.MkdirAll(filepath.Join(root, "services/web/src"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		tool       string
+		dir        string
+		constraint string
+		source     string
+	}{
+		{"go.mod in parent", "go", "services/web/src", ">=1.22", "go.mod"},
+		{"nvmrc preferred over package.json", "node", "services/web/src", "20", "services/web/.nvmrc"},
+		{"composer require php", "php", "services/api", "^8.1", "services/api/composer.json"},
+		{"tool-versions nodejs", "node", "tools", "18.19.0", "tools/.tool-versions"},
+		{"tool-versions before parent go.mod", "go", "tools", "1.24.1", "tools/.tool-versions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hint, _, err := findVersionHint(tt.tool, filepath.Join(root, tt.dir))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hint == nil {
+				t.Fatal("expected hint, got nil")
+			}
+			if hint.Constraint != tt.constraint {
+				t.Errorf("constraint = %q, want %q", hint.Constraint, tt.constraint)
+			}
+			if hint.Source != filepath.Join(root, tt.source) {
+				t.Errorf("source = %q, want %q", hint.Source, filepath.Join(root, tt.source))
+			}
+		})
+	}
+
+	// Aliases and unparsable constraints are skipped like missing hints
+	writeFile("alias/.nvmrc", "lts/iron\n")
+	writeFile("alias/package.json", `{"engines": {"node": ">=20"}}`)
+	writeFile("star/.nvmrc", "lts/*\n")
+	hint, ignored, err := findVersionHint("node", filepath.Join(root, "alias"))
+	if err != nil || hint == nil || hint.Constraint != ">=20" {
+		t.Errorf("alias .nvmrc: hint = %+v, err = %v, want package.json's >=20", hint, err)
+	}
+	if len(ignored) != 1 || !contains(ignored[0], "ignoring node version 'lts/iron' in "+filepath.Join(root, "alias/.nvmrc")) {
+		t.Errorf("alias .nvmrc: ignored = %q, want the alias reported", ignored)
+	}
+	hint, ignored, err = findVersionHint("node", filepath.Join(root, "star"))
+	if err != nil || hint != nil || len(ignored) != 1 {
+		t.Errorf("lts/* .nvmrc: hint = %+v, ignored = %q, err = %v, want no hint and one ignored", hint, ignored, err)
+	}
+
+	hint, _, err = findVersionHint("php", filepath.Join(root, "services/web"))
+	if err != nil || hint != nil {
+		t.Errorf("expected no php hint, got %v, %v", hint, err)
+	}
+}
+
+func TestSelectContainer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "composer.json"), []byte(`{"require": {"php": "^8.2"}}`), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	selectBy := &SelectBy{
+		Tool: "php",
+		Candidates: []Candidate{
+			{Container: "php81", Version: "8.1"},
+			{Container: "php83", Version: "8.3"},
+		},
+	}
+
+	container, hint, _, err := selectBy.SelectContainer(dir, "php-default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if container != "php83" || hint == nil {
+		t.Errorf("container = %q, hint = %v, want php83", container, hint)
+	}
+
+	container, _, _, err = selectBy.SelectContainer(t.TempDir(), "php-default")
+	if err != nil || container != "php-default" {
+		t.Errorf("without hint: container = %q, err = %v, want fallback", container, err)
+	}
+
+	// An alias falls back to the command's container instead of failing
+	aliased := t.TempDir()
+	if err := os.WriteFile(filepath.Join(aliased, ".nvmrc"), []byte("node\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	nodeBy := &SelectBy{Tool: "node", Candidates: []Candidate{{Container: "node20", Version: "20"}}}
+	container, hint, ignored, err := nodeBy.SelectContainer(aliased, "node-default")
+	if err != nil || container != "node-default" || hint != nil || len(ignored) != 1 {
+		t.Errorf("alias: container = %q, hint = %v, ignored = %q, err = %v, want fallback with the alias reported", container, hint, ignored, err)
+	}
+
+	// A spaced operator is a usable hint, not an ignored one
+	if err := os.WriteFile(filepath.Join(aliased, ".nvmrc"), []byte(">= 18\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	container, _, ignored, err = nodeBy.SelectContainer(aliased, "node-default")
+	if err != nil || container != "node20" || len(ignored) != 0 {
+		t.Errorf(">= 18: container = %q, ignored = %q, err = %v, want node20", container, ignored, err)
+	}
+
+	selectBy.Candidates = selectBy.Candidates[:1]
+	_, _, _, err = selectBy.SelectContainer(dir, "php-default")
+	if err == nil || !contains(err.Error(), "no container satisfies php ^8.2") || !contains(err.Error(), "php81 (8.1)") {
+		t.Errorf("expected error listing candidates, got %v", err)
+	}
+}
+
+func TestSelectByValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		selectBy      SelectBy
+		errorContains string
+	}{
+		{"valid", SelectBy{Tool: "go", Candidates: []Candidate{{Container: "go124", Version: "1.24"}}}, ""},
+		{"unknown tool", SelectBy{Tool: "ruby", Candidates: []Candidate{{Container: "r", Version: "3"}}}, "unsupported tool"},
+		{"no candidates", SelectBy{Tool: "go"}, "candidates"},
+		{"missing container", SelectBy{Tool: "go", Candidates: []Candidate{{Version: "1.24"}}}, "container"},
+		{"bad version", SelectBy{Tool: "go", Candidates: []Candidate{{Container: "go", Version: "latest"}}}, "invalid version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.selectBy.Validate()
+			if tt.errorContains == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.errorContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errorContains, err)
+			}
+		})
+	}
+}
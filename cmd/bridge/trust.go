@@ -0,0 +1,341 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// trustStore records the approved contents of config files, by absolute
+// path. The contents are kept so the bridge can fall back to them when a
+// file changes without being approved again.
+type trustStore struct {
+	Files map[string]trustedFile `json:"files"`
+}
+
+// trustedFile is the approved version of one config file.
+type trustedFile struct {
+	SHA256  string `json:"sha256"`
+	Content string `json:"content"`
+}
+
+// trustPolicyPath is the policy file that turns trust enforcement on. It
+// is a fixed path rather than an environment variable so that the agent,
+// which controls its environment, cannot turn enforcement off.
+var trustPolicyPath = "/etc/bridge/trust.json"
+
+// trustOwnerUID is the owner required of the policy and trust store files
+// and their directories. Tests change it to their own user.
+var trustOwnerUID uint32 = 0
+
+// trustPolicy is the contents of the policy file.
+type trustPolicy struct {
+	Store  string            `json:"store"`  // Trust store; default trusted.json next to the policy
+	SHA256 []string          `json:"sha256"` // SHA-256 sums approving the project config file without the store
+	Env    map[string]string `json:"env"`    // Values of ${VAR} in config files, in place of the environment
+}
+
+// readTrustPolicy reads the policy file. It returns nil when the file does
+// not exist, in which case trust is not required.
+func readTrustPolicy() (*trustPolicy, error) {
+	if _, err := os.Stat(trustPolicyPath); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err := checkRootOwned(trustPolicyPath); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(trustPolicyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust policy: %w", err)
+	}
+	policy := &trustPolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("invalid trust policy %s: %w", trustPolicyPath, err)
+	}
+	return policy, nil
+}
+
+// storePath returns the trust store file of the policy. A nil policy uses
+// the default location, so that files can be trusted before enforcement is
+// turned on.
+func (p *trustPolicy) storePath() string {
+	if p != nil && p.Store != "" {
+		return p.Store
+	}
+	return filepath.Join(filepath.Dir(trustPolicyPath), "trusted.json")
+}
+
+// pinnedHashes returns the policy's SHA-256 sums, which approve the project
+// config file without the trust store.
+func (p *trustPolicy) pinnedHashes() map[string]bool {
+	pinned := make(map[string]bool)
+	for _, sum := range p.SHA256 {
+		if sum = strings.ToLower(strings.TrimSpace(sum)); sum != "" {
+			pinned[sum] = true
+		}
+	}
+	return pinned
+}
+
+// checkRootOwned returns an error unless path, if it exists, and its
+// directory are owned by root and writable by no one else. A file the
+// agent could write would let it approve its own changes.
+func checkRootOwned(path string) error {
+	for _, p := range []string{path, filepath.Dir(path)} {
+		info, err := os.Stat(p)
+		if errors.Is(err, os.ErrNotExist) && p == path {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", p, err)
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok || stat.Uid != trustOwnerUID {
+			return fmt.Errorf("refusing %s: it must be owned by root", p)
+		}
+		if info.Mode().Perm()&0o022 != 0 {
+			return fmt.Errorf("refusing %s: it must not be writable by group or others (mode %s)", p, info.Mode().Perm())
+		}
+	}
+	return nil
+}
+
+// readTrustStore reads the trust store at path. A missing store is empty.
+func readTrustStore(path string) (*trustStore, error) {
+	store := &trustStore{Files: make(map[string]trustedFile)}
+	if path == "" {
+		return store, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust store: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid trust store %s: %w", path, err)
+	}
+	if store.Files == nil {
+		store.Files = make(map[string]trustedFile)
+	}
+	return store, nil
+}
+
+// write saves the store at path, creating its directory if needed.
+func (s *trustStore) write(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// sha256Hex returns the hex SHA-256 sum of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// trustChecker supplies config file contents as approved: a file matching
+// a store entry or pin as it is, a changed file as its trusted version, and
+// an untrusted file as empty, so that no unapproved content is parsed.
+type trustChecker struct {
+	store     *trustStore
+	pinned    map[string]bool
+	project   string // Absolute path of the file the pins apply to
+	env       map[string]string
+	warnings  []string // One per file replaced by its trusted version
+	untrusted []string
+}
+
+// read returns the approved contents of path.
+func (t *trustChecker) read(path string) ([]byte, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	if t.trusts(abs, sha256Hex(data)) {
+		return data, nil
+	}
+	if entry, ok := t.store.Files[abs]; ok && sha256Hex([]byte(entry.Content)) == entry.SHA256 && t.trusts(abs, entry.SHA256) {
+		t.warnings = append(t.warnings, fmt.Sprintf("%s has changed since it was trusted; using the trusted version (run 'bridge config trust' to approve the change)", path))
+		return []byte(entry.Content), nil
+	}
+	t.untrusted = append(t.untrusted, path)
+	return nil, nil
+}
+
+// lookupEnv returns the policy's value for a ${VAR} reference. The agent
+// controls the environment, so expanding from it would let the agent change
+// a trusted file's meaning without changing its contents.
+func (t *trustChecker) lookupEnv(name string) (string, bool) {
+	value, ok := t.env[name]
+	return value, ok
+}
+
+// err returns an error naming every untrusted file read.
+func (t *trustChecker) err() error {
+	if len(t.untrusted) > 0 {
+		return fmt.Errorf("untrusted config file(s) %s; review them and run 'bridge config trust'", strings.Join(t.untrusted, ", "))
+	}
+	return nil
+}
+
+// trusts reports whether contents with the given sum are approved for the
+// file. With pins set, the project file must match a pin.
+func (t *trustChecker) trusts(abs, sum string) bool {
+	if abs == t.project && len(t.pinned) > 0 {
+		return t.pinned[sum]
+	}
+	entry, ok := t.store.Files[abs]
+	return ok && entry.SHA256 == sum
+}
+
+// loadTrusted resolves, reads and merges the config layers for configPath
+// like loadLayers, reading only approved file contents. Files are checked
+// as they are read, so the includes of a changed file are those of its
+// trusted version.
+func loadTrusted(configPath string, policy *trustPolicy) (*loadedConfig, error) {
+	storePath := policy.storePath()
+	if err := checkRootOwned(storePath); err != nil {
+		return nil, err
+	}
+	store, err := readTrustStore(storePath)
+	if err != nil {
+		return nil, err
+	}
+	project, _ := editTarget(configPath)
+	if abs, err := filepath.Abs(project); err == nil {
+		project = abs
+	}
+	checker := &trustChecker{store: store, pinned: policy.pinnedHashes(), project: project, env: policy.Env}
+
+	loaded, err := loadLayersWith(configPath, checker)
+	if err := checker.err(); err != nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range checker.warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	return loaded, nil
+}
+
+// configTrustCommand implements `bridge config trust`, which approves the
+// current contents of every config file after showing what changed.
+func configTrustCommand(args []string, configPath string) int {
+	fs := flag.NewFlagSet("config trust", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "Trust the changes without asking (root only)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	// The question only guards against trusting changes by accident; an
+	// agent can get a terminal too. What keeps it from approving its own
+	// changes is that it cannot write the root-owned store.
+	root := os.Geteuid() == 0
+	if *yes && !root {
+		fmt.Fprintln(os.Stderr, "Error: --yes requires root")
+		return 1
+	}
+	if !*yes && !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintln(os.Stderr, "Error: config trust asks for confirmation on a terminal; run it in one, or as root with --yes")
+		return 1
+	}
+
+	policy, err := readTrustPolicy()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	storePath := policy.storePath()
+	store, err := readTrustStore(storePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+
+	loaded, err := loadLayers(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	diags, _ := diagnoseConfig(loaded)
+	var errs []string
+	for _, d := range diags {
+		if d.Severity == severityError {
+			errs = append(errs, d.String())
+		}
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: not trusting an invalid config:\n  %s\n", strings.Join(errs, "\n  "))
+		return 1
+	}
+
+	changed := make(map[string]trustedFile)
+	for _, file := range loaded.Files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			abs = file
+		}
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read %s: %s\n", file, err)
+			return 1
+		}
+		entry := trustedFile{SHA256: sha256Hex(data), Content: string(data)}
+		old, ok := store.Files[abs]
+		if ok && old.SHA256 == entry.SHA256 {
+			continue
+		}
+		writeDiff(os.Stdout, file, "(trusted)", "(current)", old.Content, entry.Content)
+		changed[abs] = entry
+	}
+	if len(changed) == 0 {
+		fmt.Println("The config is already trusted")
+		return 0
+	}
+
+	if !*yes && !confirm(os.Stdin, os.Stdout, "Trust these changes?") {
+		return 1
+	}
+
+	for abs, entry := range changed {
+		store.Files[abs] = entry
+	}
+	if err := store.write(storePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Trusted %d file(s) in %s\n", len(changed), storePath)
+	if policy == nil {
+		fmt.Printf("Trust is not enforced until %s exists\n", trustPolicyPath)
+	}
+	if project, err := editTarget(configPath); err == nil {
+		if abs, err := filepath.Abs(project); err == nil {
+			if entry, ok := store.Files[abs]; ok {
+				fmt.Printf("SHA-256 of %s: %s (add it to sha256 in %s to pin it)\n", project, entry.SHA256, trustPolicyPath)
+			}
+		}
+	}
+	return 0
+}
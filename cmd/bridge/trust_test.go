@@ -0,0 +1,288 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const trustedConfig = `version: "2"
+commands:
+  npm:
+    container: node
+    exec: [npm]
+`
+
+// setTrustPolicy writes a trust policy for the test and makes the test's
+// user count as root for the ownership checks.
+func setTrustPolicy(t *testing.T, policy string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "trust.json")
+	if policy != "" {
+		if err := os.WriteFile(path, []byte(policy), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldPath, oldUID := trustPolicyPath, trustOwnerUID
+	trustPolicyPath, trustOwnerUID = path, uint32(os.Getuid())
+	t.Cleanup(func() { trustPolicyPath, trustOwnerUID = oldPath, oldUID })
+	return path
+}
+
+func TestLoadConfigTrust(t *testing.T) {
+	project := t.TempDir()
+	configFile := filepath.Join(project, "bridge.yaml")
+	storePath := filepath.Join(t.TempDir(), "trusted.json")
+	writeFiles(t, project, map[string]string{"bridge.yaml": trustedConfig})
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("SIDECAR_CONFIG_DIR", project)
+	t.Setenv("BRIDGE_CONFIG", "")
+	t.Setenv("BRIDGE_PROFILE", "")
+	policyPath := setTrustPolicy(t, `{"store": "`+storePath+`"}`)
+
+	if _, err := LoadConfig("", ""); err == nil || !contains(err.Error(), "untrusted config file(s) "+configFile) {
+		t.Fatalf("expected untrusted error before trusting, got %v", err)
+	}
+
+	store := &trustStore{Files: map[string]trustedFile{
+		configFile: {SHA256: sha256Hex([]byte(trustedConfig)), Content: trustedConfig},
+	}}
+	if err := store.write(storePath); err != nil {
+		t.Fatalf("write store: %v", err)
+	}
+	config, err := LoadConfig("", "")
+	if err != nil {
+		t.Fatalf("LoadConfig trusted: %v", err)
+	}
+	if config.Commands["npm"].Container != "node" {
+		t.Errorf("npm container = %q, want node", config.Commands["npm"].Container)
+	}
+
+	// A changed file falls back to the trusted version
+	writeFiles(t, project, map[string]string{
+		"bridge.yaml": trustedConfig + "  sh:\n    container: node\n    exec: [sh]\n",
+	})
+	config, err = LoadConfig("", "")
+	if err != nil {
+		t.Fatalf("LoadConfig changed: %v", err)
+	}
+	if _, ok := config.Commands["sh"]; ok {
+		t.Error("sh command from the untrusted change should not be loaded")
+	}
+
+	// A fragment no one trusted is refused without being parsed
+	writeFiles(t, project, map[string]string{"bridge.d/extra.yaml": "commands: [not yaml\n"})
+	if _, err := LoadConfig("", ""); err == nil || !contains(err.Error(), "untrusted config file(s) "+filepath.Join(project, "bridge.d", "extra.yaml")) {
+		t.Errorf("expected untrusted fragment error, got %v", err)
+	}
+	if err := os.Remove(filepath.Join(project, "bridge.d", "extra.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A store the agent could write is refused
+	if err := os.Chmod(storePath, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig("", ""); err == nil || !contains(err.Error(), "refusing "+storePath) {
+		t.Errorf("expected writable store error, got %v", err)
+	}
+
+	// Trust is not required without the policy file
+	if err := os.Remove(policyPath); err != nil {
+		t.Fatal(err)
+	}
+	config, err = LoadConfig("", "")
+	if err != nil {
+		t.Fatalf("LoadConfig without trust: %v", err)
+	}
+	if _, ok := config.Commands["sh"]; !ok {
+		t.Error("sh command should load when trust is not required")
+	}
+}
+
+func TestLoadConfigTrustInterpolation(t *testing.T) {
+	project := t.TempDir()
+	config := `version: "2"
+commands:
+  npm:
+    container: ${NODE_CONTAINER:-node}
+    exec: [npm]
+  go:
+    container: ${TOOLS}
+    exec: [go]
+`
+	writeFiles(t, project, map[string]string{"bridge.yaml": config})
+	storePath := filepath.Join(t.TempDir(), "trusted.json")
+	store := &trustStore{Files: map[string]trustedFile{
+		filepath.Join(project, "bridge.yaml"): {SHA256: sha256Hex([]byte(config)), Content: config},
+	}}
+	if err := store.write(storePath); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("SIDECAR_CONFIG_DIR", project)
+	t.Setenv("BRIDGE_CONFIG", "")
+	t.Setenv("BRIDGE_PROFILE", "")
+	t.Setenv("NODE_CONTAINER", "agent-chosen")
+	t.Setenv("TOOLS", "agent-chosen")
+	setTrustPolicy(t, `{"store": "`+storePath+`", "env": {"TOOLS": "tools"}}`)
+
+	// ${VAR} expands from the policy, never from the environment
+	loaded, err := LoadConfig("", "")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if got := loaded.Commands["npm"].Container; got != "node" {
+		t.Errorf("npm container = %q, want the default node", got)
+	}
+	if got := loaded.Commands["go"].Container; got != "tools" {
+		t.Errorf("go container = %q, want tools from the policy", got)
+	}
+	if !loaded.Trusted {
+		t.Error("Trusted = false, want true")
+	}
+}
+
+func TestReadTrustPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		mode    os.FileMode
+		owner   int
+		want    string
+		wantErr string
+	}{
+		{name: "default store", policy: `{"sha256": ["ABC"]}`, mode: 0o644, want: "trusted.json"},
+		{name: "store", policy: `{"store": "/srv/trusted.json"}`, mode: 0o644, want: "/srv/trusted.json"},
+		{name: "group writable", policy: `{}`, mode: 0o664, wantErr: "must not be writable by group or others"},
+		{name: "not owned by root", policy: `{}`, mode: 0o644, owner: 1, wantErr: "must be owned by root"},
+		{name: "invalid", policy: `{"store": 1}`, mode: 0o644, wantErr: "invalid trust policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setTrustPolicy(t, tt.policy)
+			if err := os.Chmod(path, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			trustOwnerUID += uint32(tt.owner)
+
+			policy, err := readTrustPolicy()
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !contains(policy.storePath(), tt.want) {
+				t.Errorf("store = %s, want %s", policy.storePath(), tt.want)
+			}
+		})
+	}
+
+	setTrustPolicy(t, "")
+	if policy, err := readTrustPolicy(); policy != nil || err != nil {
+		t.Errorf("missing policy = %+v, %v; want nil", policy, err)
+	}
+}
+
+func TestConfigTrustCommandNeedsTerminalOrRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"bridge.yaml": trustedConfig})
+	setTrustPolicy(t, "")
+
+	// go test runs without a terminal on stdin
+	if code := configTrustCommand(nil, filepath.Join(dir, "bridge.yaml")); code != 1 {
+		t.Errorf("config trust without a terminal = %d, want 1", code)
+	}
+	if os.Geteuid() != 0 {
+		if code := configTrustCommand([]string{"--yes"}, filepath.Join(dir, "bridge.yaml")); code != 1 {
+			t.Errorf("config trust --yes as non-root = %d, want 1", code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(trustPolicyPath), "trusted.json")); err == nil {
+		t.Error("the trust store should not be written")
+	}
+}
+
+func TestTrustCheckerPins(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"bridge.yaml": trustedConfig, "bridge.local.yaml": "commands: {}\n"})
+	project := filepath.Join(dir, "bridge.yaml")
+	local := filepath.Join(dir, "bridge.local.yaml")
+	sum := sha256Hex([]byte(trustedConfig))
+
+	tests := []struct {
+		name     string
+		store    map[string]trustedFile
+		pinned   map[string]bool
+		files    []string
+		replaced int
+		wantErr  string
+	}{
+		{
+			name:   "pinned project file",
+			pinned: map[string]bool{sum: true},
+			files:  []string{project},
+		},
+		{
+			name:    "pin overrides the store",
+			store:   map[string]trustedFile{project: {SHA256: sum, Content: trustedConfig}},
+			pinned:  map[string]bool{"0000": true},
+			files:   []string{project},
+			wantErr: "untrusted config file(s) " + project,
+		},
+		{
+			name:    "pin covers only the project file",
+			pinned:  map[string]bool{sum: true},
+			files:   []string{project, local},
+			wantErr: "untrusted config file(s) " + local,
+		},
+		{
+			name:     "fallback must match the pin",
+			store:    map[string]trustedFile{project: {SHA256: sha256Hex([]byte("old")), Content: "old"}},
+			pinned:   map[string]bool{sha256Hex([]byte("old")): true},
+			files:    []string{project},
+			replaced: 1,
+		},
+		{
+			name:    "tampered store content",
+			store:   map[string]trustedFile{project: {SHA256: sha256Hex([]byte("old")), Content: "new"}},
+			files:   []string{project},
+			wantErr: "untrusted config file(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &trustStore{Files: tt.store}
+			if store.Files == nil {
+				store.Files = map[string]trustedFile{}
+			}
+			checker := &trustChecker{store: store, pinned: tt.pinned, project: project}
+			for _, file := range tt.files {
+				if _, err := checker.read(file); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := checker.err()
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(checker.warnings) != tt.replaced {
+				t.Errorf("replaced files = %d, want %d", len(checker.warnings), tt.replaced)
+			}
+		})
+	}
+}
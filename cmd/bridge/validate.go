@@ -0,0 +1,391 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity levels of a Diagnostic.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// Diagnostic is a problem found in the config, located at the file, line and
+// column of the value it refers to.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Severity string
+	Message  string
+}
+
+// String formats the diagnostic as "file:line:column: severity: message".
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", d.File, d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// configProblem is a validation problem together with the config path it
+// refers to, e.g. ["commands", "npm", "routes", "0"].
+type configProblem struct {
+	Path    []string
+	Message string
+}
+
+// ValidationError reports every problem found by Validate.
+type ValidationError struct {
+	Problems []configProblem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].Message
+	}
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Message
+	}
+	return fmt.Sprintf("%d problems:\n  %s", len(msgs), strings.Join(msgs, "\n  "))
+}
+
+// problem returns a configProblem for path with a formatted message.
+func problem(path []string, format string, args ...interface{}) configProblem {
+	return configProblem{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// subPath returns path extended by elems without aliasing path's array.
+func subPath(path []string, elems ...string) []string {
+	return append(append([]string{}, path...), elems...)
+}
+
+// diagnoseConfig checks a merged config tree: the schema (unknown keys and
+// wrong value kinds), the semantic rules of Validate and the warnings of
+// lint. Unknown keys do not stop the other checks, so every problem is
+// reported at once. The decoded config is returned when there are no errors.
+func diagnoseConfig(loaded *loadedConfig) ([]Diagnostic, *Config) {
+	var problems []configProblem
+	checkSchema(loaded.Root, reflect.TypeOf(Config{}), nil, &problems)
+	diags := loaded.diagnostics(problems, severityError)
+
+	var config Config
+	if err := loaded.Root.Decode(&config); err != nil {
+		// Values of the wrong kind are already reported by checkSchema
+		if len(diags) == 0 {
+			file := strings.Join(loaded.Files, ", ")
+			var yamlErr *yaml.TypeError
+			if !errors.As(err, &yamlErr) {
+				return []Diagnostic{{File: file, Severity: severityError, Message: err.Error()}}, nil
+			}
+			for _, msg := range yamlErr.Errors {
+				diags = append(diags, Diagnostic{File: file, Severity: severityError, Message: msg})
+			}
+		}
+		sortDiagnostics(diags)
+		return diags, nil
+	}
+	config.Sources = loaded.Files
+	config.Origins = loaded.Sources
+
+	diags = append(diags, loaded.diagnostics(config.expandPresets(), severityError)...)
+	diags = append(diags, loaded.diagnostics(config.problems(), severityError)...)
+	diags = append(diags, loaded.diagnostics(config.lint(), severityWarning)...)
+	sortDiagnostics(diags)
+	if hasErrors(diags) {
+		return diags, nil
+	}
+	return diags, &config
+}
+
+// diagnostics locates each problem in the tree.
+func (l *loadedConfig) diagnostics(problems []configProblem, severity string) []Diagnostic {
+	diags := make([]Diagnostic, 0, len(problems))
+	for _, p := range problems {
+		node := findNode(l.Root, p.Path)
+		file := l.nodeFiles[node]
+		if file == "" && len(l.Files) > 0 {
+			file = l.Files[len(l.Files)-1]
+		}
+		diags = append(diags, Diagnostic{
+			File:     file,
+			Line:     node.Line,
+			Column:   node.Column,
+			Severity: severity,
+			Message:  p.Message,
+		})
+	}
+	return diags
+}
+
+// findNode returns the node for a config path. Mapping entries resolve to
+// their key node; when part of the path does not exist (for example a field
+// inherited through extends) the deepest node found is returned.
+func findNode(root *yaml.Node, path []string) *yaml.Node {
+	pos, cur := root, root
+	for _, elem := range path {
+		switch cur.Kind {
+		case yaml.MappingNode:
+			idx := mappingIndex(cur, elem)
+			if idx < 0 {
+				return pos
+			}
+			pos, cur = cur.Content[idx], cur.Content[idx+1]
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(elem)
+			if err != nil || i < 0 || i >= len(cur.Content) {
+				return pos
+			}
+			pos, cur = cur.Content[i], cur.Content[i]
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// sortDiagnostics orders diagnostics by file and position.
+func sortDiagnostics(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i], diags[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// hasErrors reports whether any diagnostic is an error.
+func hasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == severityError {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSchema compares a node against the Go type it decodes into, reporting
+// unknown keys (with a suggestion for likely typos) and values of the wrong
+// kind. Keys are matched against the yaml struct tags.
+func checkSchema(node *yaml.Node, t reflect.Type, path []string, problems *[]configProblem) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if isNull(node) {
+		return
+	}
+	if t == reflect.TypeOf(PathMap{}) && node.Kind == yaml.ScalarNode && node.Value == autoPaths {
+		return
+	}
+	where := strings.Join(path, ".")
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			*problems = append(*problems, problem(path, "%s: expected a mapping, got %s", where, nodeKindName(node)))
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			field, ok := fields[key]
+			if !ok {
+				msg := fmt.Sprintf("unknown key '%s'", key)
+				if where != "" {
+					msg = where + ": " + msg
+				}
+				if s := suggestKey(key, fields); s != "" {
+					msg += fmt.Sprintf(" (did you mean '%s'?)", s)
+				}
+				*problems = append(*problems, configProblem{Path: subPath(path, key), Message: msg})
+				continue
+			}
+			checkSchema(node.Content[i+1], field, subPath(path, key), problems)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			*problems = append(*problems, problem(path, "%s: expected a mapping, got %s", where, nodeKindName(node)))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkSchema(node.Content[i+1], t.Elem(), subPath(path, node.Content[i].Value), problems)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			*problems = append(*problems, problem(path, "%s: expected a list, got %s", where, nodeKindName(node)))
+			return
+		}
+		for i, item := range node.Content {
+			checkSchema(item, t.Elem(), subPath(path, strconv.Itoa(i)), problems)
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			*problems = append(*problems, problem(path, "%s: expected a string, got %s", where, nodeKindName(node)))
+		}
+	}
+}
+
+// yamlFields maps the yaml keys of a struct type to their field types.
+// Fields tagged "-" are skipped.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// nodeKindName describes a node kind for error messages.
+func nodeKindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("'%s'", node.Value)
+	}
+}
+
+// suggestKey returns the known key closest to key, if it is within two edits.
+func suggestKey(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		d := editDistance(key, name)
+		if d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// lint returns warnings for settings that are valid but likely mistakes:
+// relative workdirs and path mappings, path prefixes that match more than
+// intended, and command names hidden by bridge subcommands.
+func (c *Config) lint() []configProblem {
+	var warnings []configProblem
+
+	for _, name := range sortedKeys(c.Containers) {
+		cc := c.Containers[name]
+		path := []string{"containers", name}
+		warnings = append(warnings, lintLocation(path, cc.Workdir, cc.Paths)...)
+	}
+
+	lintCommands := func(path []string, commands map[string]Command, topLevel bool) {
+		for _, name := range sortedKeys(commands) {
+			cmd := commands[name]
+			cmdPath := subPath(path, name)
+			if topLevel && reservedCommands[name] {
+				warnings = append(warnings, problem(cmdPath,
+					"command '%s' is shadowed by the 'bridge %s' subcommand; run it through its wrapper or as 'bridge -- %s'", name, name, name))
+			}
+			warnings = append(warnings, lintLocation(cmdPath, cmd.Workdir, cmd.Paths)...)
+			for i, route := range cmd.Routes {
+				warnings = append(warnings, lintLocation(subPath(cmdPath, "routes", strconv.Itoa(i)), route.Workdir, route.Paths)...)
+			}
+		}
+	}
+
+	lintCommands([]string{"commands"}, c.Commands, true)
+	for _, prefix := range sortedKeys(c.Scopes) {
+		lintCommands([]string{"scopes", prefix, "commands"}, c.Scopes[prefix].Commands, false)
+	}
+	for _, name := range c.profileNames() {
+		profile := c.Profiles[name]
+		for _, cname := range sortedKeys(profile.Containers) {
+			cc := profile.Containers[cname]
+			warnings = append(warnings, lintLocation([]string{"profiles", name, "containers", cname}, cc.Workdir, cc.Paths)...)
+		}
+		lintCommands([]string{"profiles", name, "commands"}, profile.Commands, true)
+	}
+
+	return warnings
+}
+
+// lintLocation checks the workdir and path mappings of a single entry.
+func lintLocation(path []string, workdir string, paths map[string]string) []configProblem {
+	var warnings []configProblem
+	where := strings.Join(path, ".")
+
+	if workdir != "" && !filepath.IsAbs(workdir) {
+		warnings = append(warnings, problem(subPath(path, "workdir"),
+			"%s: workdir '%s' is relative; it is resolved against the container's default directory", where, workdir))
+	}
+
+	sources := sortedKeys(PathMap(paths).explicit())
+	for _, source := range sources {
+		if !filepath.IsAbs(source) {
+			warnings = append(warnings, problem(subPath(path, "paths", source),
+				"%s: path prefix '%s' is relative and will only match relative arguments", where, source))
+		}
+		if target := paths[source]; !filepath.IsAbs(target) {
+			warnings = append(warnings, problem(subPath(path, "paths", source),
+				"%s: path target '%s' is relative", where, target))
+		}
+	}
+
+	for _, a := range sources {
+		for _, b := range sources {
+			if a != b && strings.HasPrefix(b, a) && !hasPathPrefix(b, a) && !strings.HasSuffix(a, "/") {
+				warnings = append(warnings, problem(subPath(path, "paths", a),
+					"%s: path prefix '%s' also matches '%s'; end it with '/' to match only its own directory", where, a, b))
+			}
+		}
+	}
+	return warnings
+}
+
+// sortedKeys returns the keys of a string-keyed map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigStrict(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantErrs  []string
+		wantCount string
+	}{
+		{
+			name: "unknown key with suggestion",
+			content: `version: "1"
+commands:
+  php:
+    container: app
+    exec: php
+    workdr: /var/www
+`,
+			wantErrs: []string{"bridge.yaml:6:5: error: commands.php: unknown key 'workdr' (did you mean 'workdir'?)"},
+		},
+		{
+			name: "unknown key without suggestion",
+			content: `version: "1"
+commands:
+  php:
+    container: app
+    exec: php
+    timeout: 30
+`,
+			wantErrs: []string{"unknown key 'timeout'"},
+		},
+		{
+			name: "unknown top-level key",
+			content: `version: "1"
+comands:
+  php:
+    container: app
+    exec: php
+`,
+			wantErrs: []string{"bridge.yaml:2:1: error: unknown key 'comands' (did you mean 'commands'?)"},
+		},
+		{
+			name: "wrong value kind",
+			content: `version: "1"
+commands:
+  php:
+    container: app
+    exec: php
+    env: [FOO]
+`,
+			wantErrs: []string{"bridge.yaml:6:5: error: commands.php.env: expected a mapping, got a list"},
+		},
+		{
+			name: "all problems reported at once",
+			content: `version: "1"
+commands:
+  npm:
+    container: node
+  php:
+    exec: php
+    path:
+      /a: /b
+`,
+			wantErrs: []string{
+				"bridge.yaml:3:3: error: command 'npm': missing required field 'exec'",
+				"bridge.yaml:5:3: error: command 'php': missing required field 'container'",
+				"bridge.yaml:7:5: error: commands.php: unknown key 'path' (did you mean 'paths'?)",
+			},
+			wantCount: "3 errors",
+		},
+		{
+			name: "nested route problem",
+			content: `version: "1"
+commands:
+  npm:
+    container: node
+    exec: npm
+    routes:
+      - match:
+          prefix: [run]
+          regex: "^run"
+`,
+			wantErrs: []string{"bridge.yaml:7:9: error: command 'npm': route 0: only one of"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.content)
+			_, err := LoadConfig(path, "")
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			for _, want := range tt.wantErrs {
+				if !contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err.Error(), want)
+				}
+			}
+			if tt.wantCount != "" && !contains(err.Error(), tt.wantCount) {
+				t.Errorf("error %q does not contain %q", err.Error(), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	config := &Config{
+		Version: "1",
+		Commands: map[string]Command{
+			"npm": {Container: "node"},
+			"php": {Exec: []string{"php"}},
+		},
+	}
+
+	err := config.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+	if len(verr.Problems) != 2 {
+		t.Fatalf("expected 2 problems, got %d: %v", len(verr.Problems), err)
+	}
+	if !contains(err.Error(), "command 'npm': missing required field 'exec'") ||
+		!contains(err.Error(), "command 'php': missing required field 'container'") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestProfileProblemLocation(t *testing.T) {
+	path := writeConfig(t, `version: "1"
+commands:
+  npm:
+    container: node
+    exec: npm
+profiles:
+  ci:
+    commands:
+      npx:
+        container: node
+`)
+	_, err := LoadConfig(path, "")
+	want := "bridge.yaml:9:7: error: profile 'ci': command 'npx': missing required field 'exec'"
+	if err == nil || !contains(err.Error(), want) {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
+
+func TestValidateConfigWarnings(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		ok      bool
+	}{
+		{
+			name: "clean config",
+			content: `version: "1"
+commands:
+  php:
+    container: app
+    exec: php
+    workdir: /var/www
+    paths:
+      /app/: /var/www/
+      /app/vendor/: /opt/vendor/
+`,
+			want: []string{"bridge.yaml: ok"},
+			ok:   true,
+		},
+		{
+			name: "relative workdir",
+			content: `version: "1"
+containers:
+  app:
+    name: app-1
+    workdir: www
+commands:
+  php:
+    container: app
+    exec: php
+`,
+			want: []string{"bridge.yaml:5:5: warning: containers.app: workdir 'www' is relative", "0 error(s), 1 warning(s)"},
+			ok:   true,
+		},
+		{
+			name: "relative path mapping",
+			content: `version: "1"
+commands:
+  php:
+    container: app
+    exec: php
+    paths:
+      src: /var/www/src
+`,
+			want: []string{"warning: commands.php: path prefix 'src' is relative"},
+			ok:   true,
+		},
+		{
+			name: "overlapping path prefixes",
+			content: `version: "1"
+commands:
+  php:
+    container: app
+    exec: php
+    paths:
+      /app: /var/www
+      /application: /srv
+`,
+			want: []string{"bridge.yaml:7:7: warning: commands.php: path prefix '/app' also matches '/application'"},
+			ok:   true,
+		},
+		{
+			name: "command shadowed by subcommand",
+			content: `version: "1"
+commands:
+  config:
+    container: app
+    exec: config
+`,
+			want: []string{"warning: command 'config' is shadowed by the 'bridge config' subcommand"},
+			ok:   true,
+		},
+		{
+			name: "errors and warnings",
+			content: `version: "1"
+commands:
+  php:
+    container: app
+    workdir: www
+`,
+			want: []string{
+				"bridge.yaml:3:3: error: command 'php': missing required field 'exec'",
+				"bridge.yaml:5:5: warning: commands.php: workdir 'www' is relative",
+				"1 error(s), 1 warning(s)",
+			},
+			ok: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.content)
+			var buf bytes.Buffer
+			ok, err := validateConfig(&buf, path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ok != tt.ok {
+				t.Errorf("ok = %v, want %v", ok, tt.ok)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output %q does not contain %q", buf.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestWarningsDoNotFailLoad(t *testing.T) {
+	path := writeConfig(t, `version: "1"
+commands:
+  php:
+    container: app
+    exec: php
+    workdir: www
+`)
+	if _, err := LoadConfig(path, ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"workdir", "workdir", 0},
+		{"workdr", "workdir", 1},
+		{"path", "paths", 1},
+		{"contianer", "container", 2},
+		{"", "env", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}